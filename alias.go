@@ -0,0 +1,67 @@
+package flagfx
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// Alias returns an fx.Option that registers alias as another name of the
+// canonical flag, e.g. -q for -quiet. Both names set the same value, and
+// when both are given the last one wins. The alias is hidden like a flag
+// passed to Hidden; instead, the usage output annotates the canonical flag
+// with "(alias: -q)", see AliasUsage. The canonical flag must be defined;
+// otherwise it is a configuration error.
+func Alias(canonical, alias string) Option {
+	return addHook(stageSetup, func(p *parser) error {
+		if p.fs.Lookup(canonical) == nil {
+			return fmt.Errorf("flagfx: flag -%s aliased by -%s is not defined", canonical, alias)
+		}
+		optionVar(p.fs, alias, fmt.Sprintf("alias for -%s", canonical), aliasValue{fs: p.fs, target: canonical})
+		p.hide(alias)
+		if p.aliases == nil {
+			p.aliases = make(map[string][]string)
+		}
+		p.aliases[canonical] = append(p.aliases[canonical], alias)
+		return nil
+	})
+}
+
+// AliasUsage returns an fx.Option that controls whether the usage output
+// annotates flags with the aliases registered with Alias. It is enabled by
+// default. Like EnvUsage, the annotation also applies to a function set with
+// Usage, as long as it prints the flags' help text.
+func AliasUsage(show bool) Option {
+	return addHook(stageSetup, func(p *parser) error {
+		p.hideAliasUsage = !show
+		return nil
+	})
+}
+
+// wrapAliasUsage wraps the usage function of the flag set so that, while it
+// runs, the help text of each flag names its aliases.
+func (p *parser) wrapAliasUsage() {
+	if len(p.aliases) == 0 || p.hideAliasUsage {
+		return
+	}
+	fs, usage := p.fs, p.fs.Usage
+	aliases := p.aliases
+	fs.Usage = func() {
+		restore := make(map[*flag.Flag]string)
+		for name, names := range aliases {
+			f := fs.Lookup(name)
+			restore[f] = f.Usage
+			f.Usage += " (alias: -" + strings.Join(names, ", -") + ")"
+		}
+		defer func() {
+			for f, u := range restore {
+				f.Usage = u
+			}
+		}()
+		if usage != nil {
+			usage()
+			return
+		}
+		defaultUsage(fs)
+	}
+}
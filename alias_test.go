@@ -0,0 +1,74 @@
+package flagfx_test
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestAlias(t *testing.T) {
+	tests := []struct {
+		args  []string
+		quiet bool
+		level string
+	}{
+		{nil, false, "info"},
+		{[]string{"-quiet"}, true, "info"},
+		{[]string{"-q"}, true, "info"},
+		{[]string{"-q", "-quiet=false"}, false, "info"},
+		{[]string{"-quiet", "-q=false"}, false, "info"},
+		{[]string{"-l", "debug"}, false, "debug"},
+		{[]string{"-log-level=warn", "-l=debug"}, false, "debug"},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		quiet := fs.Bool("quiet", false, "be quiet")
+		level := fs.String("log-level", "info", "log level")
+		res, err := flagfx.ParseArgs(fs, tt.args, flagfx.Alias("quiet", "q"), flagfx.Alias("log-level", "l"))
+		if err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if *quiet != tt.quiet || *level != tt.level {
+			t.Errorf("%q: -quiet=%v -log-level=%s, want %v and %s", tt.args, *quiet, *level, tt.quiet, tt.level)
+		}
+		if _, ok := res.Values["q"]; ok {
+			t.Errorf("%q: Values holds the hidden alias -q", tt.args)
+		}
+	}
+}
+
+func TestAliasUsage(t *testing.T) {
+	for _, show := range []bool{true, false} {
+		var out strings.Builder
+		fs := newTestFlagSet()
+		fs.SetOutput(&out)
+		fs.Bool("quiet", false, "be quiet")
+		_, err := flagfx.ParseArgs(fs, []string{"-h"},
+			flagfx.Alias("quiet", "q"), flagfx.Alias("quiet", "silent"), flagfx.AliasUsage(show))
+		if !errors.Is(err, flag.ErrHelp) {
+			t.Fatalf("error %v, want flag.ErrHelp", err)
+		}
+		usage := out.String()
+		want := "\tbe quiet (alias: -q, -silent)\n"
+		if !show {
+			want = "\tbe quiet\n"
+		}
+		if !strings.Contains(usage, want) {
+			t.Errorf("AliasUsage(%v): usage %q does not contain %q", show, usage, want)
+		}
+		if strings.Contains(usage, "-q\n") || strings.Contains(usage, "-silent\n") {
+			t.Errorf("AliasUsage(%v): usage %q lists the aliases as flags", show, usage)
+		}
+	}
+}
+
+func TestAliasUndefined(t *testing.T) {
+	_, err := flagfx.ParseArgs(newTestFlagSet(), nil, flagfx.Alias("quiet", "q"))
+	if want := "flagfx: flag -quiet aliased by -q is not defined"; err == nil || err.Error() != want {
+		t.Errorf("error %v, want %q", err, want)
+	}
+}
@@ -0,0 +1,349 @@
+package flagfx
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"go.uber.org/fx"
+)
+
+// ArgsString is like Args, but takes the command line as a single string that
+// is split into arguments like a POSIX shell does: arguments are separated by
+// unquoted whitespace, single quotes preserve their contents literally, double
+// quotes allow escaping ", \, $ and ` with a backslash, and an unquoted
+// backslash escapes the following character. No expansion takes place.
+// An empty string yields no arguments, and unbalanced quotes fail the app.
+//
+//	flagfx.ArgsString(`-name "Jane Doe" -greeting 'hello, world'`)
+func ArgsString(s string) fx.Option {
+	args, err := splitArgs(s)
+	if err != nil {
+		return fx.Error(fmt.Errorf("flagfx: split arguments %q: %w", s, err))
+	}
+	return Args(args)
+}
+
+// ArgsFromEnv returns an fx.Option that prepends the arguments held by the
+// named environment variable to Arguments, like the go command does with
+// GOFLAGS. The value is split like ArgsString does, and since the last value
+// of a flag wins, the arguments given on the command line override the ones
+// from the environment. An unset or empty variable adds no arguments, and
+// unbalanced quotes fail the app. The variable is looked up with the
+// EnvLookup, so Env replaces the environment in tests.
+//
+//	flagfx.ArgsFromEnv("APPFLAGS") // APPFLAGS="-log-level=debug -name 'Jane Doe'"
+func ArgsFromEnv(varName string) Option {
+	return addHook(stageArgs, func(p *parser) error {
+		value, ok := p.env(varName)
+		if !ok {
+			return nil
+		}
+		args, err := splitArgs(value)
+		if err != nil {
+			return fmt.Errorf("flagfx: split arguments of $%s: %w", varName, err)
+		}
+		p.args = append(Arguments(args), p.args...)
+		return nil
+	})
+}
+
+// splitArgs splits s into arguments following the quoting rules of a POSIX
+// shell.
+func splitArgs(s string) ([]string, error) {
+	args := []string{}
+	var (
+		arg   strings.Builder
+		inArg bool // Whether arg holds an argument, which may be empty.
+	)
+	rs := []rune(s)
+	for i := 0; i < len(rs); i++ {
+		switch r := rs[i]; r {
+		case ' ', '\t', '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		case '\\':
+			inArg = true
+			i++
+			if i == len(rs) {
+				return nil, errors.New("trailing backslash")
+			}
+			if rs[i] != '\n' { // A backslash-newline is a line continuation.
+				arg.WriteRune(rs[i])
+			}
+		case '\'':
+			inArg = true
+			end := slices.Index(rs[i+1:], '\'')
+			if end < 0 {
+				return nil, errors.New("unterminated single quote")
+			}
+			arg.WriteString(string(rs[i+1 : i+1+end]))
+			i += end + 1
+		case '"':
+			inArg = true
+			for i++; ; i++ {
+				if i == len(rs) {
+					return nil, errors.New("unterminated double quote")
+				}
+				if rs[i] == '"' {
+					break
+				}
+				if rs[i] == '\\' && i+1 < len(rs) && strings.ContainsRune("\"\\$`\n", rs[i+1]) {
+					i++
+					if rs[i] == '\n' {
+						continue
+					}
+				}
+				arg.WriteRune(rs[i])
+			}
+		default:
+			inArg = true
+			arg.WriteRune(r)
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// CaseInsensitive returns an fx.Option that matches the flags given in the
+// Arguments to the registered flags regardless of case, so -LOG-LEVEL sets
+// -log-level. Before parsing, the name of each flag is rewritten to the
+// registered spelling, up to the first non-flag argument or "--", where the
+// flag package stops parsing. A name that exactly matches a flag is used as
+// is; otherwise, matching two flags that only differ in case fails the app
+// with a ValidationError.
+func CaseInsensitive() Option {
+	return addHook(stageArgs, func(p *parser) error {
+		args, err := resolveFlagNames(p.fs, p.args, func(f *flag.Flag, name string) bool {
+			return strings.EqualFold(f.Name, name)
+		})
+		if err != nil {
+			return err
+		}
+		p.args = args
+		return nil
+	})
+}
+
+// AllowAbbrev returns an fx.Option that accepts unambiguous prefixes of flag
+// names, so -log stands for -log-level unless another flag starts with "log"
+// as well. Like CaseInsensitive, it rewrites the flags in Arguments before
+// parsing, up to the first non-flag argument or "--". A name that exactly
+// matches a flag is used as is; otherwise, a prefix of two or more flags
+// fails the app with a ValidationError listing them. Hidden flags are never
+// matched by a prefix, so that aliases such as those of Deprecated don't make
+// prefixes ambiguous.
+//
+// Abbreviations can surprise users, and adding a flag may break an
+// abbreviation that used to work, so the option is opt-in.
+func AllowAbbrev() Option {
+	return addHook(stageArgs, func(p *parser) error {
+		args, err := resolveFlagNames(p.fs, p.args, func(f *flag.Flag, name string) bool {
+			return strings.HasPrefix(f.Name, name) && !p.isHidden(f.Name)
+		})
+		if err != nil {
+			return err
+		}
+		p.args = args
+		return nil
+	})
+}
+
+// StripArgPrefix returns an fx.Option that removes the given prefix from the
+// flags in Arguments before parsing, so -app.log-level and --app.log-level
+// set -log-level, for a sub-app consuming its part of a larger command line.
+// It undoes Prefix for flags passed in by a host. Flags without the prefix are
+// left as is, and so are the values of flags given as the next argument, as
+// in -name -app.x, and the arguments following "--".
+func StripArgPrefix(prefix string) Option {
+	return addHook(stageArgs, func(p *parser) error {
+		p.args = stripArgPrefix(p.fs, p.args, prefix)
+		return nil
+	})
+}
+
+// stripArgPrefix removes the prefix from the flag names in args up to "--",
+// skipping the values of the flags of fs that take the next argument.
+func stripArgPrefix(fs *flag.FlagSet, args Arguments, prefix string) Arguments {
+	args = slices.Clone(args)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			continue
+		}
+		for _, dashes := range []string{"--", "-"} {
+			if name, ok := strings.CutPrefix(arg, dashes+prefix+"."); ok && name != "" {
+				args[i] = dashes + name
+				break
+			}
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if f := fs.Lookup(name); f != nil && !hasValue && !isBoolFlag(f) {
+			i++ // Skip the value of the flag.
+		}
+	}
+	return args
+}
+
+// Interspersed returns an fx.Option that parses flags wherever they appear in
+// Arguments, like GNU getopt, rather than stopping at the first non-flag
+// argument, so "a -x b -y c" sets -x and -y and leaves the positional
+// arguments a, b and c, in this order, in Positional. Before parsing, the
+// flags, with their values, are moved ahead of the positional arguments.
+// "--" still ends the flags, and the arguments following it are left as is.
+//
+// Whether a flag takes the following argument as its value depends on the
+// registered flag, so options rewriting flag names, such as CaseInsensitive,
+// must be listed before Interspersed; an unknown flag is assumed to take no
+// value, and reported by the flag package as usual.
+func Interspersed() Option {
+	return addHook(stageArgs, func(p *parser) error {
+		p.args = intersperse(p.fs, p.args)
+		return nil
+	})
+}
+
+// intersperse reorders args so that the flags before "--" precede the
+// positional arguments.
+func intersperse(fs *flag.FlagSet, args Arguments) Arguments {
+	var flags, positional Arguments
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			positional = append(positional, args[i:]...)
+			break
+		}
+		if len(arg) < 2 || arg[0] != '-' {
+			positional = append(positional, arg)
+			continue
+		}
+		flags = append(flags, arg)
+		name, _, hasValue := strings.Cut(strings.TrimPrefix(arg[1:], "-"), "=")
+		if f := fs.Lookup(name); f != nil && !hasValue && !isBoolFlag(f) && i+1 < len(args) {
+			i++
+			flags = append(flags, args[i])
+		}
+	}
+	return append(flags, positional...)
+}
+
+// resolveFlagNames rewrites the names of the flags in args that are not
+// registered with the flag set to the name of the only registered flag
+// matching them, as reported by matches.
+func resolveFlagNames(fs *flag.FlagSet, args Arguments, matches func(f *flag.Flag, name string) bool) (Arguments, error) {
+	args = slices.Clone(args)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || arg[0] != '-' || arg == "--" {
+			break
+		}
+		dashes := "-"
+		if arg[1] == '-' {
+			dashes = "--"
+		}
+		name, value, hasValue := strings.Cut(arg[len(dashes):], "=")
+		f := fs.Lookup(name)
+		if f == nil {
+			var found []*flag.Flag
+			fs.VisitAll(func(f *flag.Flag) {
+				if matches(f, name) {
+					found = append(found, f)
+				}
+			})
+			if len(found) > 1 {
+				names := make([]string, len(found))
+				for j, m := range found {
+					names[j] = m.Name
+				}
+				return nil, validationErrorf("flagfx: flag -%s is ambiguous, it matches %s", name, joinFlags(names))
+			}
+			if len(found) == 0 {
+				// Leave it to the flag package to report the unknown flag.
+				continue
+			}
+			f = found[0]
+			args[i] = dashes + f.Name
+			if hasValue {
+				args[i] += "=" + value
+			}
+		}
+		if !hasValue && !isBoolFlag(f) {
+			i++ // Skip the value of the flag.
+		}
+	}
+	return args, nil
+}
+
+// maxResponseFileDepth limits how deeply response files may reference each other.
+const maxResponseFileDepth = 10
+
+// ResponseFiles returns an fx.Option that expands response files in the
+// Arguments before parsing: an argument of the form @path is replaced by the
+// contents of the file, one argument per line. Lines are used verbatim, without
+// shell-style quoting, except that trailing carriage returns are removed and
+// empty lines are skipped. Arguments read from a file may reference further
+// response files, up to a depth of 10 to prevent loops. Arguments following
+// "--" are never expanded, even if the "--" was read from a response file,
+// and neither are values attached to a flag with "=", such as the @path of
+// -token=@path for DefineSecret.
+func ResponseFiles() Option {
+	return addHook(stageArgs, func(p *parser) error {
+		args, _, err := expandResponseFiles(p.args, 0)
+		if err != nil {
+			return err
+		}
+		p.args = args
+		return nil
+	})
+}
+
+// expandResponseFiles expands the response files referenced by args. It
+// reports whether it stopped at a "--", after which nothing is expanded, so
+// that a "--" in a response file also ends the expansion of the arguments
+// referencing it.
+func expandResponseFiles(args Arguments, depth int) (Arguments, bool, error) {
+	var expanded Arguments
+	for i, arg := range args {
+		if arg == "--" {
+			return append(expanded, args[i:]...), true, nil
+		}
+		path, ok := strings.CutPrefix(arg, "@")
+		if !ok || path == "" {
+			expanded = append(expanded, arg)
+			continue
+		}
+		if depth >= maxResponseFileDepth {
+			return nil, false, fmt.Errorf("flagfx: response file %s: nested too deeply", path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, false, fmt.Errorf("flagfx: read response file: %w", err)
+		}
+		var lines Arguments
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimRight(line, "\r"); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		lines, stopped, err := expandResponseFiles(lines, depth+1)
+		if err != nil {
+			return nil, false, err
+		}
+		expanded = append(expanded, lines...)
+		if stopped {
+			return append(expanded, args[i+1:]...), true, nil
+		}
+	}
+	return expanded, false, nil
+}
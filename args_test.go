@@ -0,0 +1,324 @@
+package flagfx_test
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestStripArgPrefix(t *testing.T) {
+	tests := []struct {
+		args    []string
+		level   string
+		name    string
+		unknown []string
+		rest    []string // Passed through after "--".
+	}{
+		{args: []string{"-app.log-level=debug"}, level: "debug"},
+		{args: []string{"--app.log-level", "debug"}, level: "debug"},
+		{args: []string{"-log-level=warn"}, level: "warn"},
+		{args: []string{"-other.log-level=warn"}, level: "info", unknown: []string{"-other.log-level=warn"}},
+		// The value of -name is left as is, even if it looks like a flag.
+		{args: []string{"-name", "-app.x", "-app.log-level=debug"}, level: "debug", name: "-app.x"},
+		{args: []string{"--", "-app.log-level=debug"}, level: "info", rest: []string{"-app.log-level=debug"}},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		level := fs.String("log-level", "info", "")
+		name := fs.String("name", "", "")
+		res, err := flagfx.ParseArgs(fs, tt.args, flagfx.StripArgPrefix("app"), flagfx.TolerateUnknown(false))
+		if err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if *level != tt.level || *name != tt.name {
+			t.Errorf("%q: -log-level=%q -name=%q, want %q and %q", tt.args, *level, *name, tt.level, tt.name)
+		}
+		if !slicesEqual(res.Unknown, tt.unknown) || !slicesEqual(res.PassThrough, tt.rest) {
+			t.Errorf("%q: Unknown = %q, PassThrough = %q, want %q and %q", tt.args, res.Unknown, res.PassThrough, tt.unknown, tt.rest)
+		}
+	}
+}
+
+func TestAllowAbbrev(t *testing.T) {
+	tests := []struct {
+		args    []string
+		level   string
+		log     bool
+		verbose bool
+		err     string
+	}{
+		{args: []string{"-log-l=debug"}, level: "debug"},
+		{args: []string{"--log-lev", "debug", "-verb"}, level: "debug", verbose: true},
+		{args: []string{"-v"}, level: "info", verbose: true},
+		// An exact match wins over the flags it is a prefix of.
+		{args: []string{"-log"}, level: "info", log: true},
+		{args: []string{"-log-"}, err: "flag -log- is ambiguous, it matches -log-file, -log-level"},
+		{args: []string{"-l=debug"}, err: "flag -l is ambiguous, it matches -log, -log-file, -log-level"},
+		// Hidden flags are not matched by a prefix.
+		{args: []string{"-sec"}, err: "flag provided but not defined: -sec"},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		level := fs.String("log-level", "info", "")
+		fs.String("log-file", "", "")
+		log := fs.Bool("log", false, "")
+		verbose := fs.Bool("verbose", false, "")
+		fs.Bool("secret", false, "")
+		_, err := flagfx.ParseArgs(fs, tt.args, flagfx.AllowAbbrev(), flagfx.Hidden("secret"))
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: error %v, want %q", tt.args, err, tt.err)
+			}
+			var verr flagfx.ValidationError
+			if strings.Contains(tt.err, "ambiguous") && !errors.As(err, &verr) {
+				t.Errorf("%q: error %v is not a ValidationError", tt.args, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if *level != tt.level || *log != tt.log || *verbose != tt.verbose {
+			t.Errorf("%q: -log-level=%s -log=%v -verbose=%v, want %s, %v and %v",
+				tt.args, *level, *log, *verbose, tt.level, tt.log, tt.verbose)
+		}
+	}
+}
+
+func TestInterspersed(t *testing.T) {
+	tests := []struct {
+		args       []string
+		x, y       bool
+		name       string
+		positional []string
+		rest       []string // Passed through after "--".
+	}{
+		{args: []string{"a", "-x", "b", "-y", "c"}, x: true, y: true, positional: []string{"a", "b", "c"}},
+		{args: []string{"a", "-name", "b", "c", "--y"}, y: true, name: "b", positional: []string{"a", "c"}},
+		{args: []string{"a", "-name=b", "c", "-x=false"}, name: "b", positional: []string{"a", "c"}},
+		{args: []string{"a", "--", "-x", "b"}, positional: []string{"a"}, rest: []string{"-x", "b"}},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		x := fs.Bool("x", false, "")
+		y := fs.Bool("y", false, "")
+		name := fs.String("name", "", "")
+		res, err := flagfx.ParseArgs(fs, tt.args, flagfx.Interspersed())
+		if err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if *x != tt.x || *y != tt.y || *name != tt.name {
+			t.Errorf("%q: -x=%v -y=%v -name=%q, want %v, %v and %q", tt.args, *x, *y, *name, tt.x, tt.y, tt.name)
+		}
+		if !slicesEqual(res.Positional, tt.positional) || !slicesEqual(res.PassThrough, tt.rest) {
+			t.Errorf("%q: Positional = %q, PassThrough = %q, want %q and %q", tt.args, res.Positional, res.PassThrough, tt.positional, tt.rest)
+		}
+	}
+}
+
+func TestArgsFromEnv(t *testing.T) {
+	tests := []struct {
+		env        map[string]string
+		args       []string
+		level      string
+		name       string
+		positional []string
+		err        string
+	}{
+		{env: nil, args: []string{"-name=cli"}, level: "info", name: "cli"},
+		{env: map[string]string{"APPFLAGS": ""}, level: "info"},
+		{env: map[string]string{"APPFLAGS": `-log-level=debug -name 'Jane Doe'`}, level: "debug", name: "Jane Doe"},
+		// The command line overrides the environment.
+		{env: map[string]string{"APPFLAGS": `-log-level=debug -name "Jane \"J\" Doe"`}, args: []string{"-name=cli", "a"},
+			level: "debug", name: "cli", positional: []string{"a"}},
+		{env: map[string]string{"APPFLAGS": `-name 'Jane`}, err: "flagfx: split arguments of $APPFLAGS: unterminated single quote"},
+		{env: map[string]string{"APPFLAGS": `-name "Jane`}, err: "flagfx: split arguments of $APPFLAGS: unterminated double quote"},
+	}
+	for _, tt := range tests {
+		var positional flagfx.Positional
+		fs := newTestFlagSet()
+		level := fs.String("log-level", "info", "")
+		name := fs.String("name", "", "")
+		app := fx.New(
+			fx.NopLogger,
+			flagfx.ModuleFor(fs, tt.args),
+			flagfx.Env(func(key string) (string, bool) {
+				v, ok := tt.env[key]
+				return v, ok
+			}),
+			flagfx.ArgsFromEnv("APPFLAGS"),
+			fx.Populate(&positional),
+		)
+		err := app.Err()
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: error %v, want %q", tt.env, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.env, err)
+			continue
+		}
+		if *level != tt.level || *name != tt.name || !slicesEqual(positional, tt.positional) {
+			t.Errorf("%q %q: -log-level=%q -name=%q Positional = %q, want %q, %q and %q",
+				tt.env, tt.args, *level, *name, positional, tt.level, tt.name, tt.positional)
+		}
+	}
+}
+
+func TestArgsFunc(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "args.txt")
+	if err := os.WriteFile(path, []byte("-name=from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	var name *string
+	opt := flagfx.ArgsFunc(func() ([]string, error) {
+		calls++
+		return []string{"-log-level=debug", "@" + path}, nil
+	})
+	if calls != 0 {
+		t.Errorf("fn called %d times when constructing the option, want it deferred", calls)
+	}
+	fs := newTestFlagSet()
+	level := fs.String("log-level", "info", "")
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(fs, []string{"-log-level=ignored"}),
+		opt,
+		flagfx.ResponseFiles(),
+		flagfx.Provide(func(fs *flag.FlagSet) *string { return fs.String("name", "", "") }),
+		fx.Populate(&name),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("fn called %d times, want 1", calls)
+	}
+	if *level != "debug" || *name != "from-file" {
+		t.Errorf("-log-level=%s -name=%s, want debug and from-file", *level, *name)
+	}
+}
+
+func TestArgsFuncError(t *testing.T) {
+	invoked := false
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), nil),
+		flagfx.ArgsFunc(func() ([]string, error) { return nil, errors.New("handshake failed") }),
+		fx.Invoke(func(flagfx.Ready) { invoked = true }),
+	)
+	if err := app.Err(); err == nil || !strings.Contains(err.Error(), "flagfx: supply arguments: handshake failed") {
+		t.Errorf("error %v, want the error of fn", err)
+	}
+	if invoked {
+		t.Error("dependent invoked despite the error of fn")
+	}
+}
+
+func TestArgsString(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []string
+		err  string
+	}{
+		{``, nil, ""},
+		{" \t\n ", nil, ""},
+		{`a  b`, []string{"a", "b"}, ""},
+		{`''`, []string{""}, ""},
+		{`"" a`, []string{"", "a"}, ""},
+		{`'a b' "c d"`, []string{"a b", "c d"}, ""},
+		{`'a'"b"c`, []string{"abc"}, ""},
+		{`a\ b`, []string{"a b"}, ""},
+		{`\'a\"`, []string{`'a"`}, ""},
+		{`'a\nb' '$HOME'`, []string{`a\nb`, "$HOME"}, ""},
+		{`"a\"b\\c\$d\` + "`" + `e\nf"`, []string{"a\"b\\c$d`e\\nf"}, ""},
+		{`"it's" 'say "hi"'`, []string{"it's", `say "hi"`}, ""},
+		{"a\\\nb", []string{"ab"}, ""},
+		{"\"a\\\nb\"", []string{"ab"}, ""},
+		{`a 'b`, nil, "unterminated single quote"},
+		{`a "b`, nil, "unterminated double quote"},
+		{`"a\"`, nil, "unterminated double quote"},
+		{`a\`, nil, "trailing backslash"},
+	}
+	for _, tt := range tests {
+		var positional flagfx.Positional
+		app := fx.New(
+			fx.NopLogger,
+			flagfx.ModuleFor(newTestFlagSet(), nil),
+			flagfx.ArgsString(tt.s),
+			fx.Populate(&positional),
+		)
+		err := app.Err()
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: error %v, want %q", tt.s, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.s, err)
+			continue
+		}
+		if !slicesEqual(positional, tt.want) {
+			t.Errorf("%q: got %q, want %q", tt.s, positional, tt.want)
+		}
+	}
+}
+
+func TestResponseFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	flags := write("flags", "-name=file\r\n\n-tag=a\n")
+	nested := write("nested", "-tag=b\n@"+flags+"\n")
+	stop := write("stop", "-tag=c\n--\n@"+flags+"\n")
+	outer := write("outer", "@"+stop+"\n@"+flags+"\n")
+	tests := []struct {
+		args        []string
+		name        string
+		tags        []string
+		passThrough []string
+	}{
+		{[]string{"@" + flags}, "file", []string{"a"}, nil},
+		{[]string{"@" + flags, "-name=cli"}, "cli", []string{"a"}, nil},
+		{[]string{"@" + nested}, "file", []string{"b", "a"}, nil},
+		{[]string{"-name=@" + flags}, "@" + flags, nil, nil},
+		{[]string{"--", "@" + flags}, "", nil, []string{"@" + flags}},
+		// A "--" in a response file stops the expansion of the arguments
+		// that follow, in the file and in the arguments referencing it.
+		{[]string{"@" + stop, "@" + flags}, "", []string{"c"}, []string{"@" + flags, "@" + flags}},
+		{[]string{"@" + outer, "@" + flags}, "", []string{"c"}, []string{"@" + flags, "@" + flags, "@" + flags}},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		name := fs.String("name", "", "")
+		tags := flagfx.DefineSlice(fs, "tag", "")
+		res, err := flagfx.ParseArgs(fs, tt.args, flagfx.ResponseFiles())
+		if err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if *name != tt.name || !slicesEqual(*tags, tt.tags) || !slicesEqual(res.PassThrough, tt.passThrough) {
+			t.Errorf("%q: -name=%q -tag=%q PassThrough = %q, want %q, %q and %q",
+				tt.args, *name, *tags, res.Positional, tt.name, tt.tags, tt.passThrough)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package flagfx
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"time"
+
+	"go.uber.org/fx"
+)
+
+// Bind registers a flag for each field of the struct T tagged with `flag:"name"`
+// and returns a pointer to the struct, which holds the flag values once parsed.
+// The `usage:"..."` tag sets the usage message and the `default:"..."` tag the
+// default value, given in the same form as on the command line. The values
+// given for a []string or map[string]string field replace its default rather
+// than extending it.
+//
+//	type config struct {
+//		Addr    string        `flag:"addr" default:":8080" usage:"listen address"`
+//		Workers int           `flag:"workers" default:"4" usage:"number of workers"`
+//		Debug   bool          `flag:"debug" usage:"enable debug mode"`
+//		Timeout time.Duration `flag:"timeout" default:"5s" usage:"request timeout"`
+//	}
+//
+// Supported field types are those of Define, []string (repeatable, see
+// DefineSlice), map[string]string (see DefineMap) and any type whose pointer
+// implements flag.Value. Tagged fields of other struct types are flattened, so
+// a field tagged "db" holding a field tagged "host" registers -db.host. A
+// non-empty prefix is prepended to every name in the same way. Fields without
+// a flag tag are ignored.
+//
+// Bind panics if T is not a struct, or for a tagged field of an unsupported
+// type or with an invalid default.
+func Bind[T any](fs *flag.FlagSet, prefix string) *T {
+	p := new(T)
+	v := reflect.ValueOf(p).Elem()
+	if v.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("flagfx: Bind requires a struct type, got %s", v.Type()))
+	}
+	bindStruct(fs, prefix, v)
+	return p
+}
+
+// FlagStruct is a struct whose tagged fields are registered as flags like
+// Bind does, contributed by a module to the value group "flagfx_structs".
+// flagfx registers the fields of every contributed struct with the active
+// flag set before parsing, so modules declare their flags without a
+// constructor of their own. Value must be a pointer to the struct, which
+// holds the flag values once parsed; BindGroup hands it back to the module.
+type FlagStruct struct {
+	Prefix string // Prepended to the flag names, see Bind.
+	Value  any    // A pointer to the tagged struct.
+}
+
+// BindGroup returns an fx.Option that contributes a new T as a FlagStruct with
+// the given prefix, and provides the *T once the flags are parsed:
+//
+//	fx.Module("server",
+//		flagfx.BindGroup[serverFlags](""),
+//		fx.Invoke(func(f *serverFlags) { ... }),
+//	)
+//
+// A new T is allocated for every app built with the option, so that several
+// apps may share it, and the *T provided is the one contributed by the app. A
+// struct that Bind does not support, or a flag that is already defined, fails
+// the app.
+func BindGroup[T any](prefix string) fx.Option {
+	return fx.Options(
+		fx.Provide(
+			func() boundStruct[T] { return boundStruct[T]{v: new(T)} },
+			fx.Annotate(
+				func(b boundStruct[T]) FlagStruct { return FlagStruct{Prefix: prefix, Value: b.v} },
+				fx.ResultTags(`group:"flagfx_structs"`),
+			),
+		),
+		Provide(func(b boundStruct[T]) *T { return b.v }),
+	)
+}
+
+// boundStruct holds the struct of an app contributed by BindGroup.
+type boundStruct[T any] struct {
+	v *T
+}
+
+// bindStructs registers the fields of the structs contributed as FlagStruct.
+func (p *parser) bindStructs() error {
+	for _, s := range p.structs {
+		if err := bindFlagStruct(p.fs, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bindFlagStruct registers the fields of the struct, converting the panics of
+// bindStruct into an error.
+func bindFlagStruct(fs *flag.FlagSet, s FlagStruct) (err error) {
+	v := reflect.ValueOf(s.Value)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("flagfx: FlagStruct requires a pointer to a struct, got %T", s.Value)
+	}
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		if name, ok := redefinedFlag(r); ok {
+			err = fmt.Errorf("flagfx: flag %q already registered by another module", name)
+		} else {
+			err = fmt.Errorf("flagfx: bind %T: %v", s.Value, r)
+		}
+	}()
+	bindStruct(fs, s.Prefix, v.Elem())
+	return nil
+}
+
+// _reflFlagValue is the reflection type of flag.Value.
+var _reflFlagValue = reflect.TypeFor[flag.Value]()
+
+// bindStruct registers the tagged fields of the struct v.
+func bindStruct(fs *flag.FlagSet, prefix string, v reflect.Value) {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, ok := field.Tag.Lookup("flag")
+		if !ok || !field.IsExported() {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct && !fv.Addr().Type().Implements(_reflFlagValue) {
+			bindStruct(fs, name, fv)
+			continue
+		}
+		bindField(fs, name, field, fv)
+	}
+}
+
+// bindField registers the flag for a single field.
+func bindField(fs *flag.FlagSet, name string, field reflect.StructField, fv reflect.Value) {
+	usage := field.Tag.Get("usage")
+	switch p := fv.Addr().Interface().(type) {
+	case flag.Value:
+		fs.Var(p, name, usage)
+	case *string:
+		fs.StringVar(p, name, "", usage)
+	case *bool:
+		fs.BoolVar(p, name, false, usage)
+	case *int:
+		fs.IntVar(p, name, 0, usage)
+	case *int64:
+		fs.Int64Var(p, name, 0, usage)
+	case *uint:
+		fs.UintVar(p, name, 0, usage)
+	case *uint64:
+		fs.Uint64Var(p, name, 0, usage)
+	case *float64:
+		fs.Float64Var(p, name, 0, usage)
+	case *time.Duration:
+		fs.DurationVar(p, name, 0, usage)
+	case *[]string:
+		fs.Var((*StringSlice)(p), name, usage)
+	case *map[string]string:
+		fs.Var((*StringMap)(p), name, usage)
+	default:
+		panic(fmt.Sprintf("flagfx: unsupported type %s of field %s for flag -%s", field.Type, field.Name, name))
+	}
+
+	f := fs.Lookup(name)
+	if def, ok := field.Tag.Lookup("default"); ok {
+		if err := seedDefault(f, []string{def}); err != nil {
+			panic(fmt.Sprintf("flagfx: invalid default %q for flag -%s: %v", def, name, err))
+		}
+	}
+	saved := reflect.ValueOf(fv.Interface())
+	seeded, _ := f.Value.(*seededValue)
+	recordDefault(fs, name, func() {
+		fv.Set(saved)
+		if seeded != nil {
+			seeded.changed = false
+		}
+	})
+}
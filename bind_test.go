@@ -0,0 +1,170 @@
+package flagfx_test
+
+import (
+	"flag"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+type bindConfig struct {
+	Addr    string        `flag:"addr" default:":8080" usage:"listen address"`
+	Workers int           `flag:"workers" default:"4" usage:"number of workers"`
+	Debug   bool          `flag:"debug" usage:"enable debug mode"`
+	Timeout time.Duration `flag:"timeout" default:"5s" usage:"request timeout"`
+	DB      struct {
+		Host string `flag:"host" default:"localhost"`
+	} `flag:"db"`
+	Ignored string
+}
+
+func TestBind(t *testing.T) {
+	fs := newTestFlagSet()
+	cfg := flagfx.Bind[bindConfig](fs, "app")
+
+	want := map[string]string{
+		"app.addr":    ":8080",
+		"app.workers": "4",
+		"app.debug":   "false",
+		"app.timeout": "5s",
+		"app.db.host": "localhost",
+	}
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) {
+		names = append(names, f.Name)
+		if def, ok := want[f.Name]; !ok || f.DefValue != def {
+			t.Errorf("-%s registered with default %q, want %q", f.Name, f.DefValue, def)
+		}
+	})
+	if len(names) != len(want) {
+		t.Errorf("registered %q, want %d flags", names, len(want))
+	}
+	if got := fs.Lookup("app.addr").Usage; got != "listen address" {
+		t.Errorf("usage of -app.addr = %q", got)
+	}
+
+	if _, err := flagfx.ParseArgs(fs, []string{"-app.workers=8", "-app.debug", "-app.db.host=db"}); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Addr != ":8080" || cfg.Workers != 8 || !cfg.Debug || cfg.Timeout != 5*time.Second || cfg.DB.Host != "db" {
+		t.Errorf("config %+v", *cfg)
+	}
+}
+
+func TestBindUnsupported(t *testing.T) {
+	type config struct {
+		C chan int `flag:"c"`
+	}
+	defer func() {
+		msg, _ := recover().(string)
+		if !strings.Contains(msg, "unsupported type chan int of field C for flag -c") {
+			t.Errorf("panic %q, want the field and flag named", msg)
+		}
+	}()
+	flagfx.Bind[config](newTestFlagSet(), "")
+}
+
+func TestBindRepeatableDefault(t *testing.T) {
+	type config struct {
+		Tags   []string          `flag:"tag" default:"a"`
+		Labels map[string]string `flag:"label" default:"env=prod,tier=web"`
+	}
+	tests := []struct {
+		args   []string
+		tags   []string
+		labels map[string]string
+	}{
+		{nil, []string{"a"}, map[string]string{"env": "prod", "tier": "web"}},
+		{[]string{"-tag=b"}, []string{"b"}, map[string]string{"env": "prod", "tier": "web"}},
+		{[]string{"-tag=b", "-tag=c", "-label=env=dev"}, []string{"b", "c"}, map[string]string{"env": "dev"}},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		cfg := flagfx.Bind[config](fs, "")
+		if _, err := flagfx.ParseArgs(fs, tt.args, flagfx.MaxOccurrences("tag", 2)); err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if !slices.Equal(cfg.Tags, tt.tags) || !maps.Equal(cfg.Labels, tt.labels) {
+			t.Errorf("%q: -tag = %q, -label = %v, want %q and %v", tt.args, cfg.Tags, cfg.Labels, tt.tags, tt.labels)
+		}
+		if got, want := fs.Lookup("tag").DefValue, "a"; got != want {
+			t.Errorf("%q: default of -tag = %q, want %q", tt.args, got, want)
+		}
+	}
+
+	// The default is replaced again once restored by Reparse.
+	fs := newTestFlagSet()
+	cfg := flagfx.Bind[config](fs, "")
+	for _, args := range [][]string{{"-tag=b"}, {"-tag=c"}} {
+		if err := flagfx.Reparse(fs, args); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"c"}; !slices.Equal(cfg.Tags, want) {
+		t.Errorf("-tag = %q after Reparse, want %q", cfg.Tags, want)
+	}
+}
+
+type serverFlags struct {
+	Addr string `flag:"addr" default:":8080"`
+}
+
+type clientFlags struct {
+	Timeout time.Duration `flag:"timeout" default:"5s"`
+	Retries int           `flag:"retries"`
+}
+
+func TestBindGroup(t *testing.T) {
+	server := fx.Module("server", flagfx.BindGroup[serverFlags]("server"))
+	client := fx.Module("client", flagfx.BindGroup[clientFlags](""))
+
+	// The options are shared by both apps, each binding structs of its own.
+	newApp := func(args ...string) (*serverFlags, *clientFlags) {
+		var s *serverFlags
+		var c *clientFlags
+		app := fx.New(
+			fx.NopLogger,
+			flagfx.ModuleFor(newTestFlagSet(), args),
+			server, client,
+			fx.Populate(&s, &c),
+		)
+		if err := app.Err(); err != nil {
+			t.Fatalf("%q: %v", args, err)
+		}
+		return s, c
+	}
+	s1, c1 := newApp("-server.addr=:9090", "-retries=3")
+	s2, c2 := newApp("-timeout=1s")
+	if s1 == s2 || c1 == c2 {
+		t.Fatal("apps share the bound structs")
+	}
+	if s1.Addr != ":9090" || c1.Timeout != 5*time.Second || c1.Retries != 3 {
+		t.Errorf("app 1: %+v %+v", *s1, *c1)
+	}
+	if s2.Addr != ":8080" || c2.Timeout != time.Second || c2.Retries != 0 {
+		t.Errorf("app 2: %+v %+v", *s2, *c2)
+	}
+}
+
+func TestBindGroupRedefined(t *testing.T) {
+	type otherFlags struct {
+		Retries string `flag:"retries"`
+	}
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), nil),
+		flagfx.BindGroup[clientFlags](""),
+		flagfx.BindGroup[otherFlags](""),
+		fx.Invoke(func(*clientFlags, *otherFlags) {}),
+	)
+	if err := app.Err(); err == nil || !strings.Contains(err.Error(), "retries") {
+		t.Errorf("error %v, want the redefined -retries named", err)
+	}
+}
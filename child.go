@@ -0,0 +1,74 @@
+package flagfx
+
+import (
+	"flag"
+	"strings"
+)
+
+// Child creates a flag set for a plugin that registers its flags at startup,
+// such as -plugin.verbose, rather than through Named. The child set shares
+// the Arguments of the active flag set: each flag given on the command line
+// is routed to the set that defines it, so a child ignores the flags of the
+// active flag set and of other children instead of failing on them.
+//
+// Child must be called from a constructor passed to Provide, which runs before
+// parsing; the child sets are parsed by the flagfx barrier right after the
+// active flag set. Calling it later panics.
+//
+//	flagfx.Provide(func(child flagfx.Child) *pluginFlags {
+//		fs := child("plugin")
+//		var f pluginFlags
+//		fs.BoolVar(&f.Verbose, "plugin.verbose", false, "verbose plugin output")
+//		return &f
+//	})
+//
+// Flag names must be unique across the active flag set and its children.
+type Child func(name string) *flag.FlagSet
+
+// newChild provides the Child function of the parser.
+func newChild(p *parser) Child {
+	return func(name string) *flag.FlagSet {
+		if p.cli != nil {
+			panic("flagfx: Child called after parsing; call it from a constructor passed to flagfx.Provide")
+		}
+		fs := flag.NewFlagSet(name, flag.ContinueOnError)
+		fs.SetOutput(p.fs.Output())
+		p.children = append(p.children, fs)
+		return fs
+	}
+}
+
+// routeChildFlags moves the flags defined by the children of the parser from
+// args into the arguments of each child, and returns the remaining arguments.
+func (p *parser) routeChildFlags(args Arguments) (Arguments, []Arguments) {
+	childArgs := make([]Arguments, len(p.children))
+	var rest Arguments
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || arg[0] != '-' || arg == "--" {
+			// The flag package stops parsing at the first non-flag argument.
+			rest = append(rest, args[i:]...)
+			break
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		owner := -1
+		f := p.fs.Lookup(name)
+		for j, child := range p.children {
+			if cf := child.Lookup(name); cf != nil && f == nil {
+				owner, f = j, cf
+				break
+			}
+		}
+		n := 1
+		if f != nil && !hasValue && !isBoolFlag(f) && i+1 < len(args) {
+			n = 2 // The value of the flag follows.
+		}
+		if owner < 0 {
+			rest = append(rest, args[i:i+n]...)
+		} else {
+			childArgs[owner] = append(childArgs[owner], args[i:i+n]...)
+		}
+		i += n - 1
+	}
+	return rest, childArgs
+}
@@ -0,0 +1,24 @@
+package flagfx
+
+import (
+	"time"
+
+	"go.uber.org/fx"
+)
+
+// Now returns the current time. Constructors registering flags whose default
+// is relative to the current time, see DefineSince, should inject Now instead
+// of calling time.Now directly, so that tests get deterministic defaults.
+type Now func() time.Time
+
+// defaultNow provides the default Now, which is time.Now.
+// This can be replaced using the Clock option.
+func defaultNow() Now {
+	return time.Now
+}
+
+// Clock allows replacing the default Now (time.Now), for example with a fixed
+// time in tests.
+func Clock(fn func() time.Time) fx.Option {
+	return fx.Replace(Now(fn))
+}
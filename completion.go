@@ -0,0 +1,181 @@
+package flagfx
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Completion writes a script that makes the given shell, bash or zsh, complete
+// the flags of the flag set for the program named by the flag set. Flag names
+// are completed after a dash, and the values of flags defined with DefineEnum
+// are completed as well. The script is meant to be sourced, e.g.
+//
+//	source <(app -completion=bash)
+func Completion(fs *flag.FlagSet, shell string, w io.Writer) error {
+	return writeCompletion(fs, shell, w, func(string) bool { return false })
+}
+
+// writeCompletion writes the completion script, leaving out the flags for
+// which hidden reports true.
+func writeCompletion(fs *flag.FlagSet, shell string, w io.Writer, hidden func(name string) bool) error {
+	prog := filepath.Base(fs.Name())
+	var flags []completionFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		if hidden(f.Name) {
+			return
+		}
+		c := completionFlag{name: f.Name, usage: firstLine(f.Usage), isBool: isBoolFlag(f)}
+		if e, ok := f.Value.(*enumValue); ok {
+			c.values = e.allowed
+		}
+		flags = append(flags, c)
+	})
+	switch shell {
+	case "bash":
+		return writeBashCompletion(w, prog, flags)
+	case "zsh":
+		return writeZshCompletion(w, prog, flags)
+	default:
+		return fmt.Errorf("flagfx: unsupported shell %q for completion, expected bash or zsh", shell)
+	}
+}
+
+// CompletionFlag returns an fx.Option that registers a flag, e.g. completion,
+// selecting a shell for which Completion writes the script to the output of
+// the flag set once the flags are parsed. The app then exits cleanly through
+// the Exiter, before any checks such as Required run. The flag itself is
+// hidden, and so are the flags passed to Hidden in the script.
+func CompletionFlag(name string) Option {
+	return options(
+		addHook(stageSetup, func(p *parser) error {
+			shells := []string{"bash", "zsh"}
+			usage := fmt.Sprintf("print the shell completion script (one of %s)", strings.Join(shells, ", "))
+			optionVar(p.fs, name, usage, enumValue{value: new(string), allowed: shells})
+			p.hide(name)
+			return nil
+		}),
+		addHook(stageInfo, func(p *parser) error {
+			// Like PrintAndExit, read the flag from the parsed flag set.
+			shell := p.fs.Lookup(name).Value.String()
+			if shell == "" {
+				return nil
+			}
+			if err := writeCompletion(p.fs, shell, p.fs.Output(), p.isHidden); err != nil {
+				return err
+			}
+			p.quit()
+			return nil
+		}),
+	)
+}
+
+// completionFlag describes a flag for the completion scripts.
+type completionFlag struct {
+	name   string
+	usage  string
+	isBool bool
+	values []string // The values to complete, if known.
+}
+
+// writeBashCompletion writes the bash completion script.
+func writeBashCompletion(w io.Writer, prog string, flags []completionFlag) error {
+	fn := "_" + shellIdent(prog) + "_completion"
+	var b strings.Builder
+	fmt.Fprintf(&b, "# bash completion for %s\n", prog)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" prev=\"${COMP_WORDS[COMP_CWORD-1]}\"\n")
+	var names []string
+	for _, f := range flags {
+		names = append(names, "-"+f.name)
+	}
+	var enums []completionFlag
+	for _, f := range flags {
+		if len(f.values) > 0 {
+			enums = append(enums, f)
+		}
+	}
+	if len(enums) > 0 {
+		b.WriteString("\tcase \"$cur\" in\n")
+		for _, f := range enums {
+			fmt.Fprintf(&b, "\t-%[1]s=*|--%[1]s=*)\n", f.name)
+			fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -P \"${cur%%%%=*}=\" -W %s -- \"${cur#*=}\"))\n", shellQuote(strings.Join(f.values, " ")))
+			b.WriteString("\t\treturn\n\t\t;;\n")
+		}
+		b.WriteString("\tesac\n")
+		b.WriteString("\tcase \"$prev\" in\n")
+		for _, f := range enums {
+			fmt.Fprintf(&b, "\t-%[1]s|--%[1]s)\n", f.name)
+			fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n", shellQuote(strings.Join(f.values, " ")))
+			b.WriteString("\t\treturn\n\t\t;;\n")
+		}
+		b.WriteString("\tesac\n")
+	}
+	b.WriteString("\tif [[ \"$cur\" == -* ]]; then\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W %s -- \"$cur\"))\n", shellQuote(strings.Join(names, " ")))
+	b.WriteString("\tfi\n")
+	b.WriteString("}\n")
+	fmt.Fprintf(&b, "complete -o default -F %s %s\n", fn, shellQuote(prog))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeZshCompletion writes the zsh completion script.
+func writeZshCompletion(w io.Writer, prog string, flags []completionFlag) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "#compdef %s\n", prog)
+	fmt.Fprintf(&b, "# zsh completion for %s\n", prog)
+	fmt.Fprintf(&b, "_%s() {\n", shellIdent(prog))
+	b.WriteString("\t_arguments")
+	for _, f := range flags {
+		spec := "-" + f.name
+		if !f.isBool {
+			spec += "="
+		}
+		spec += "[" + zshEscape(f.usage) + "]"
+		switch {
+		case len(f.values) > 0:
+			spec += ":" + f.name + ":(" + strings.Join(f.values, " ") + ")"
+		case !f.isBool:
+			spec += ":" + f.name + ":_default"
+		}
+		fmt.Fprintf(&b, " \\\n\t\t%s", shellQuote(spec))
+	}
+	b.WriteString("\n}\n")
+	fmt.Fprintf(&b, "compdef _%s %s\n", shellIdent(prog), shellQuote(prog))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// isBoolFlag reports whether the flag needs no argument, like the flag package
+// does when parsing.
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// firstLine returns the first line of s.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+// nonIdent matches the characters that are not valid in a shell function name.
+var nonIdent = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// shellIdent turns the program name into a valid shell identifier.
+func shellIdent(prog string) string {
+	return nonIdent.ReplaceAllString(prog, "_")
+}
+
+// shellQuote quotes s for a POSIX shell with single quotes.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// zshEscape escapes the characters that are special in the description of
+// an _arguments spec.
+var zshEscape = strings.NewReplacer(`[`, `\[`, `]`, `\]`, `:`, `\:`).Replace
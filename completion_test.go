@@ -0,0 +1,55 @@
+package flagfx_test
+
+import (
+	"bytes"
+	"flag"
+	"testing"
+
+	"github.com/lftk/flagfx"
+)
+
+func newCompletionFlagSet(out *bytes.Buffer) *flag.FlagSet {
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.SetOutput(out)
+	fs.String("addr", ":8080", "address to listen on")
+	fs.Bool("verbose", false, "verbose output\nwith a second line")
+	flagfx.DefineEnum(fs, "format", "text", []string{"text", "json"}, "output format")
+	fs.String("token", "", "secret token")
+	return fs
+}
+
+func TestCompletionFlag(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh"} {
+		t.Run(shell, func(t *testing.T) {
+			var out bytes.Buffer
+			fs := newCompletionFlagSet(&out)
+			_, err := flagfx.ParseArgs(fs, []string{"-completion=" + shell},
+				flagfx.CompletionFlag("completion"),
+				flagfx.Hidden("token"),
+				flagfx.Required("addr"), // Skipped, as the app exits first.
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, "completion."+shell, out.Bytes())
+		})
+	}
+}
+
+func TestCompletionFlagUnset(t *testing.T) {
+	var out bytes.Buffer
+	fs := newCompletionFlagSet(&out)
+	if _, err := flagfx.ParseArgs(fs, nil, flagfx.CompletionFlag("completion")); err != nil {
+		t.Fatal(err)
+	}
+	if out.Len() > 0 {
+		t.Errorf("completion written without the flag:\n%s", out.Bytes())
+	}
+}
+
+func TestCompletionUnsupportedShell(t *testing.T) {
+	var out bytes.Buffer
+	if err := flagfx.Completion(newCompletionFlagSet(&out), "fish", &out); err == nil {
+		t.Error("fish: no error")
+	}
+}
@@ -0,0 +1,90 @@
+package flagfx
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ConfigFile returns an fx.Option that loads default flag values from a file
+// of key=value lines, where each key is the name of a flag. Blank lines and
+// lines starting with # are ignored.
+//
+// The precedence is command line > environment (see EnvPrefix) > config file >
+// flag default: values from the file only apply to flags that are still unset.
+// A line that cannot be applied, such as one whose key does not name a flag,
+// is an error identifying the file, the line and the key, and restating the
+// precedence, e.g.
+//
+//	flagfx: config file app.conf:3: unknown flag "prot" (precedence: ...)
+//
+// where the precedence reads "command line > environment > config file".
+func ConfigFile(path string) Option {
+	return addHook(stageFile, func(p *parser) error {
+		return p.applyConfigFile(path)
+	})
+}
+
+// ConfigFileFlag returns an fx.Option that registers a string flag holding the
+// path of a config file, which is then loaded like ConfigFile. The file is
+// read after parsing, so the path itself follows the usual precedence and can
+// be given on the command line or, with EnvPrefix, in the environment.
+// Nothing is loaded when the flag is empty.
+func ConfigFileFlag(name, usage string) Option {
+	return options(
+		addHook(stageSetup, func(p *parser) error {
+			optionVar(p.fs, name, usage, optionString(""))
+			return nil
+		}),
+		addHook(stageFile, func(p *parser) error {
+			path := p.fs.Lookup(name).Value.String()
+			if path == "" {
+				return nil
+			}
+			return p.applyConfigFile(path)
+		}),
+	)
+}
+
+// applyConfigFile sets the unset flags to the values found in the file.
+func (p *parser) applyConfigFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("flagfx: read config file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return configError(path, line, "expected key=value")
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if p.fs.Lookup(key) == nil {
+			return configError(path, line, "unknown flag %q", key)
+		}
+		if p.set[key] {
+			// Already set on the command line or by a fallback with a higher precedence.
+			continue
+		}
+		if err := p.setFallback(key, value, SourceConfig); err != nil {
+			return configError(path, line, "invalid value %q for flag -%s: %v", value, key, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("flagfx: read config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// configError returns the error for the line of the config file.
+func configError(path string, line int, format string, args ...any) error {
+	return fmt.Errorf("flagfx: config file %s:%d: %s (precedence: command line > environment > config file)",
+		path, line, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,64 @@
+package flagfx_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lftk/flagfx"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "app.conf")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigFile(t *testing.T) {
+	path := writeConfig(t, "# defaults\n\nport = 8080\nhost=example.com\n")
+	fs := newTestFlagSet()
+	port := fs.Int("port", 80, "")
+	host := fs.String("host", "localhost", "")
+	res, err := flagfx.ParseArgs(fs, []string{"-host=cli.example.com"}, flagfx.ConfigFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *port != 8080 || *host != "cli.example.com" {
+		t.Errorf("-port=%d -host=%s, want the port of the file and the host of the command line", *port, *host)
+	}
+	if res.Sources["port"] != flagfx.SourceConfig {
+		t.Errorf("Sources[port] = %v, want SourceConfig", res.Sources["port"])
+	}
+}
+
+func TestConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"port", ":1: expected key=value"},
+		{"\nprot=8080", `:2: unknown flag "prot"`},
+		{"port=x", `:1: invalid value "x" for flag -port`},
+	}
+	for _, tt := range tests {
+		path := writeConfig(t, tt.content)
+		fs := newTestFlagSet()
+		fs.Int("port", 80, "")
+		_, err := flagfx.ParseArgs(fs, nil, flagfx.ConfigFile(path))
+		if err == nil {
+			t.Errorf("%q: no error", tt.content)
+			continue
+		}
+		msg := err.Error()
+		if !strings.Contains(msg, "config file "+path+tt.want) {
+			t.Errorf("%q: error %q does not contain %q", tt.content, msg, path+tt.want)
+		}
+		if !strings.Contains(msg, "command line > environment > config file") {
+			t.Errorf("%q: error %q does not state the precedence", tt.content, msg)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package flagfx
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+
+	"go.uber.org/fx"
+)
+
+// parseContext holds the context that bounds the barrier.
+type parseContext struct {
+	ctx context.Context
+}
+
+// defaultParseContext provides the default context of the barrier, which is
+// never canceled. This can be replaced using the ParseContext option.
+func defaultParseContext() parseContext {
+	return parseContext{ctx: context.Background()}
+}
+
+// ParseContext returns an fx.Option that bounds the barrier by ctx. Once ctx is
+// done, the remaining hooks are skipped and the app fails with the context's
+// error, so a timeout can abort a slow startup, e.g. a hook fetching values
+// from a remote source.
+//
+// The context is not the one of the lifecycle: the barrier runs while fx.New
+// builds the graph, before the app is started, so the context later passed to
+// app.Start, and to OnStart hooks, does not exist yet. Derive ctx from the
+// same parent to bound both, e.g.
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//	app := fx.New(flagfx.Module, flagfx.ParseContext(ctx), ...)
+//	err := app.Start(ctx)
+func ParseContext(ctx context.Context) fx.Option {
+	return fx.Replace(parseContext{ctx: ctx})
+}
+
+// AfterParseContext is like AfterParse, but fn receives the context of the
+// barrier set by the ParseContext option. Long-running hooks should honor its
+// cancellation and return its error.
+func AfterParseContext(fn func(ctx context.Context, fs *flag.FlagSet) error) Option {
+	return addHook(stageParsed, func(p *parser) error {
+		return fn(p.ctx, p.fs)
+	})
+}
+
+// ConfigSourceContext returns an fx.Option that loads default flag values from
+// the name=value pairs returned by fn, such as a remote configuration service.
+// It runs after ConfigFile and, like it, only applies values to flags that
+// are still unset. fn receives the context of the barrier set by the
+// ParseContext option and should honor its cancellation.
+func ConfigSourceContext(fn func(ctx context.Context, fs *flag.FlagSet) (map[string]string, error)) Option {
+	return addHook(stageFile, func(p *parser) error {
+		values, err := fn(p.ctx, p.fs)
+		if err != nil {
+			return fmt.Errorf("flagfx: load config: %w", err)
+		}
+		for _, name := range slices.Sorted(maps.Keys(values)) {
+			if p.fs.Lookup(name) == nil {
+				return fmt.Errorf("flagfx: config: unknown flag %q", name)
+			}
+			if p.set[name] {
+				continue
+			}
+			if err := p.setFallback(name, values[name], SourceConfig); err != nil {
+				return fmt.Errorf("flagfx: config: invalid value %q for flag -%s: %v", values[name], name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// checkContext reports an error once the context of the barrier is done.
+func (p *parser) checkContext() error {
+	if err := p.ctx.Err(); err != nil {
+		return fmt.Errorf("flagfx: parse flags: %w", context.Cause(p.ctx))
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package flagfx_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"testing"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+type ctxKey struct{}
+
+func TestParseContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKey{}, "v")
+	var got any
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), nil),
+		flagfx.ParseContext(ctx),
+		flagfx.AfterParseContext(func(ctx context.Context, _ *flag.FlagSet) error {
+			got = ctx.Value(ctxKey{})
+			return nil
+		}),
+		fx.Invoke(func(flagfx.Ready) {}),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if got != "v" {
+		t.Errorf("AfterParseContext got a context without the value, %v", got)
+	}
+}
+
+func TestParseContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	called := false
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), nil),
+		flagfx.ParseContext(ctx),
+		flagfx.ConfigSourceContext(func(context.Context, *flag.FlagSet) (map[string]string, error) {
+			called = true
+			return nil, nil
+		}),
+		fx.Invoke(func(flagfx.Ready) {}),
+	)
+	if err := app.Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf("app error %v, want context.Canceled", err)
+	}
+	if called {
+		t.Error("ConfigSourceContext was called with a canceled context")
+	}
+}
@@ -0,0 +1,185 @@
+package flagfx
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Defaults returns an fx.Option that overrides the defaults of registered
+// flags with the fields of the struct v, or of the struct v points to, which
+// keeps the defaults in one place instead of scattered across registrations.
+// Fields are matched to flags with the `flag:"name"` tag and nested structs are
+// flattened like Bind does, so the struct type given to Bind can seed its own
+// defaults:
+//
+//	flagfx.Defaults(config{Addr: ":9090", Timeout: 10 * time.Second})
+//
+// Fields holding their zero value are skipped and leave the registered default
+// in place. The values given for a []string or map[string]string field
+// replace its default rather than extending it. The defaults are applied before parsing and are shown in the usage
+// output; the command line and fallbacks such as EnvPrefix still take
+// precedence. A field naming a flag that is not defined, of an unsupported
+// type, or holding a value the flag rejects is a configuration error.
+func Defaults(v any) Option {
+	return addHook(stageSetup, func(p *parser) error {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() == reflect.Pointer {
+			rv = rv.Elem()
+		}
+		if rv.Kind() != reflect.Struct {
+			return fmt.Errorf("flagfx: Defaults requires a struct, got %T", v)
+		}
+		return applyDefaults(p.fs, "", rv)
+	})
+}
+
+// Override returns an fx.Option that sets the named flag to value once the
+// command line and fallbacks such as EnvPrefix and ConfigFile have been
+// applied, whether or not the flag was set, e.g. to change a single flag in an
+// integration test without rebuilding the arguments. Overrides apply in
+// registration order, before checks such as Required and Validate, which
+// therefore see the overridden value, and Sources reports SourceOverride.
+// Naming a flag that is not defined in the flag set, or a value the flag
+// rejects, is a configuration error.
+func Override(name, value string) Option {
+	return addHook(stageSetup, func(p *parser) error {
+		p.overrides = append(p.overrides, override{name: name, value: value})
+		return nil
+	})
+}
+
+// override is a value forced onto a flag with Override.
+type override struct {
+	name, value string
+}
+
+// applyOverrides applies the overrides registered with Override.
+func (p *parser) applyOverrides() error {
+	for _, o := range p.overrides {
+		if err := lookupAll(p.fs, "overridden", []string{o.name}); err != nil {
+			return err
+		}
+		if err := p.setFallback(o.name, o.value, SourceOverride); err != nil {
+			return fmt.Errorf("flagfx: invalid override %q for flag -%s: %v", o.value, o.name, err)
+		}
+	}
+	return nil
+}
+
+// DeriveDefault returns an fx.Option that computes the value of the named flag
+// from other flags when it was not set, e.g. to default -cache-dir to
+// <data-dir>/cache. fn runs once the command line and fallbacks such as
+// EnvPrefix and ConfigFile have been applied, so it sees the final values of
+// the flags it depends on, and before checks such as Required and Validate.
+// Derivations run in registration order, so a derivation may depend on a flag
+// derived before it. Naming a flag that is not defined in the flag set, or
+// deriving a value the flag rejects, is a configuration error.
+//
+// A derived value counts as a default: Sources reports it as SourceDefault,
+// Lookup does not report the flag as set, and it does not satisfy Required.
+func DeriveDefault(name string, fn func(fs *flag.FlagSet) string) Option {
+	return addHook(stageSetup, func(p *parser) error {
+		p.derived = append(p.derived, derivedDefault{name: name, fn: fn})
+		return nil
+	})
+}
+
+// derivedDefault is a flag whose default is derived from other flags.
+type derivedDefault struct {
+	name string
+	fn   func(fs *flag.FlagSet) string
+}
+
+// applyDerived applies the defaults registered with DeriveDefault to the
+// flags that are still unset.
+func (p *parser) applyDerived() error {
+	for _, d := range p.derived {
+		if err := lookupAll(p.fs, "derived", []string{d.name}); err != nil {
+			return err
+		}
+		if p.set[d.name] {
+			continue
+		}
+		// Set the value directly rather than through the flag set, which
+		// would mark the flag as set, like Defaults does.
+		value := d.fn(p.fs)
+		if err := p.fs.Lookup(d.name).Value.Set(value); err != nil {
+			return fmt.Errorf("flagfx: invalid derived default %q for flag -%s: %v", value, d.name, err)
+		}
+	}
+	return nil
+}
+
+// applyDefaults sets the defaults of the flags named by the tagged fields of
+// the struct v.
+func applyDefaults(fs *flag.FlagSet, prefix string, v reflect.Value) error {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, ok := field.Tag.Lookup("flag")
+		if !ok || !field.IsExported() {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct && !reflect.PointerTo(fv.Type()).Implements(_reflFlagValue) {
+			if err := applyDefaults(fs, name, fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if fv.IsZero() {
+			continue
+		}
+		values, err := defaultValues(fv)
+		if err != nil {
+			return fmt.Errorf("flagfx: %v of field %s for flag -%s", err, field.Name, name)
+		}
+		f := fs.Lookup(name)
+		if f == nil {
+			return fmt.Errorf("flagfx: default for flag -%s is not defined", name)
+		}
+		if err := seedDefault(f, values); err != nil {
+			return fmt.Errorf("flagfx: invalid default %q for flag -%s: %v", strings.Join(values, ","), name, err)
+		}
+		chainDefault(fs, name, func() { seedDefault(f, values) })
+	}
+	return nil
+}
+
+// defaultValues returns the field value in the form given on the command line.
+// A []string yields one value per element, as for a repeated flag.
+func defaultValues(fv reflect.Value) ([]string, error) {
+	// Copy the field, which may not be addressable, to call String on types
+	// whose pointer implements flag.Value.
+	p := reflect.New(fv.Type())
+	p.Elem().Set(fv)
+	if v, ok := p.Interface().(flag.Value); ok {
+		return []string{v.String()}, nil
+	}
+	switch v := fv.Interface().(type) {
+	case string:
+		return []string{v}, nil
+	case bool:
+		return []string{strconv.FormatBool(v)}, nil
+	case int, int64, uint, uint64:
+		return []string{fmt.Sprint(v)}, nil
+	case float64:
+		return []string{strconv.FormatFloat(v, 'g', -1, 64)}, nil
+	case time.Duration:
+		return []string{v.String()}, nil
+	case []string:
+		return v, nil
+	case map[string]string:
+		return []string{(*StringMap)(&v).String()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %s", fv.Type())
+	}
+}
@@ -0,0 +1,210 @@
+package flagfx_test
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+func deriveCacheDir(fs *flag.FlagSet) string {
+	return filepath.Join(fs.Lookup("data-dir").Value.String(), "cache")
+}
+
+func TestDeriveDefault(t *testing.T) {
+	tests := []struct {
+		args   []string
+		want   string
+		set    bool
+		source flagfx.Source
+	}{
+		{nil, "/var/lib/app/cache", false, flagfx.SourceDefault},
+		{[]string{"-data-dir=/data"}, "/data/cache", false, flagfx.SourceDefault},
+		{[]string{"-data-dir=/data", "-cache-dir=/tmp"}, "/tmp", true, flagfx.SourceCommandLine},
+	}
+	for _, tt := range tests {
+		var lookup flagfx.Lookup
+		var sources flagfx.Sources
+		fs := newTestFlagSet()
+		fs.String("data-dir", "/var/lib/app", "")
+		cacheDir := fs.String("cache-dir", "", "")
+		app := fx.New(
+			fx.NopLogger,
+			flagfx.ModuleFor(fs, tt.args),
+			flagfx.DeriveDefault("cache-dir", deriveCacheDir),
+			fx.Populate(&lookup, &sources),
+		)
+		if err := app.Err(); err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if *cacheDir != tt.want {
+			t.Errorf("%q: -cache-dir = %s, want %s", tt.args, *cacheDir, tt.want)
+		}
+		if lookup.Set("cache-dir") != tt.set {
+			t.Errorf("%q: Lookup.Set(cache-dir) = %v, want %v", tt.args, !tt.set, tt.set)
+		}
+		if sources["cache-dir"] != tt.source {
+			t.Errorf("%q: Sources[cache-dir] = %v, want %v", tt.args, sources["cache-dir"], tt.source)
+		}
+	}
+}
+
+func TestDeriveDefaultRequired(t *testing.T) {
+	fs := newTestFlagSet()
+	fs.String("data-dir", "/var/lib/app", "")
+	fs.String("cache-dir", "", "")
+	_, err := flagfx.ParseArgs(fs, nil, flagfx.DeriveDefault("cache-dir", deriveCacheDir), flagfx.Required("cache-dir"))
+	var verr flagfx.ValidationError
+	if !errors.As(err, &verr) {
+		t.Errorf("error %v, want the derived -cache-dir to leave it missing", err)
+	}
+}
+
+type defaultsConfig struct {
+	Addr    string            `flag:"addr"`
+	Workers int               `flag:"workers"`
+	Debug   bool              `flag:"debug"`
+	Timeout time.Duration     `flag:"timeout"`
+	Tags    []string          `flag:"tag"`
+	Labels  map[string]string `flag:"label"`
+	DB      struct {
+		Host string `flag:"host"`
+	} `flag:"db"`
+}
+
+func newDefaultsFlagSet() *flag.FlagSet {
+	fs := newTestFlagSet()
+	fs.String("addr", ":8080", "")
+	fs.Int("workers", 4, "")
+	fs.Bool("debug", false, "")
+	fs.Duration("timeout", 5*time.Second, "")
+	flagfx.DefineSlice(fs, "tag", "")
+	flagfx.DefineMap(fs, "label", "")
+	fs.String("db.host", "localhost", "")
+	return fs
+}
+
+func TestDefaults(t *testing.T) {
+	defaults := defaultsConfig{
+		Addr:    ":9090",
+		Timeout: time.Minute,
+		Tags:    []string{"a", "b"},
+		Labels:  map[string]string{"env": "prod"},
+	}
+	defaults.DB.Host = "db"
+	tests := []struct {
+		args []string
+		want map[string]string
+	}{
+		{nil, map[string]string{
+			"addr": ":9090", "workers": "4", "debug": "false", "timeout": "1m0s",
+			"tag": "a,b", "label": "env=prod", "db.host": "db",
+		}},
+		{[]string{"-addr=:7070", "-tag=c", "-label=tier=web", "-db.host=other"}, map[string]string{
+			"addr": ":7070", "workers": "4", "debug": "false", "timeout": "1m0s",
+			"tag": "c", "label": "tier=web", "db.host": "other",
+		}},
+	}
+	for _, tt := range tests {
+		fs := newDefaultsFlagSet()
+		res, err := flagfx.ParseArgs(fs, tt.args, flagfx.Defaults(&defaults), flagfx.MaxOccurrences("tag", 1))
+		if err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if !maps.Equal(res.Values, tt.want) {
+			t.Errorf("%q: Values = %v, want %v", tt.args, res.Values, tt.want)
+		}
+		// The defaults are shown in the usage output.
+		if got := fs.Lookup("addr").DefValue; got != ":9090" {
+			t.Errorf("%q: default of -addr = %q, want :9090", tt.args, got)
+		}
+		if got := fs.Lookup("tag").DefValue; got != "a,b" {
+			t.Errorf("%q: default of -tag = %q, want a,b", tt.args, got)
+		}
+	}
+}
+
+func TestDefaultsErrors(t *testing.T) {
+	tests := []struct {
+		v    any
+		want string
+	}{
+		{42, "flagfx: Defaults requires a struct, got int"},
+		{struct {
+			Missing string `flag:"missing"`
+		}{"x"}, "flagfx: default for flag -missing is not defined"},
+		{struct {
+			Workers string `flag:"workers"`
+		}{"many"}, `flagfx: invalid default "many" for flag -workers`},
+		{struct {
+			C chan int `flag:"addr"`
+		}{make(chan int)}, "flagfx: unsupported type chan int of field C for flag -addr"},
+	}
+	for _, tt := range tests {
+		_, err := flagfx.ParseArgs(newDefaultsFlagSet(), nil, flagfx.Defaults(tt.v))
+		if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+			t.Errorf("%T: error %v, want %q", tt.v, err, tt.want)
+		}
+	}
+}
+
+func TestOverride(t *testing.T) {
+	tests := []struct {
+		args      []string
+		overrides [][2]string
+		level     string
+		source    flagfx.Source
+		err       string
+	}{
+		{nil, nil, "info", flagfx.SourceDefault, ""},
+		{nil, [][2]string{{"log-level", "debug"}}, "debug", flagfx.SourceOverride, ""},
+		{[]string{"-log-level=warn"}, [][2]string{{"log-level", "debug"}}, "debug", flagfx.SourceOverride, ""},
+		{nil, [][2]string{{"log-level", "debug"}, {"log-level", "error"}}, "error", flagfx.SourceOverride, ""},
+		{nil, [][2]string{{"log-level", "trace"}}, "", 0, `invalid value "trace"`},
+		{nil, [][2]string{{"workers", "x"}}, "", 0, `invalid override "x" for flag -workers`},
+		{nil, [][2]string{{"missing", "x"}}, "", 0, "-missing"},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		level := fs.String("log-level", "info", "")
+		fs.Int("workers", 4, "")
+		opts := []flagfx.Option{flagfx.Validate(map[string]func(string) error{
+			"log-level": func(value string) error {
+				if value == "trace" {
+					return fmt.Errorf("invalid value %q", value)
+				}
+				return nil
+			},
+		})}
+		for _, o := range tt.overrides {
+			opts = append(opts, flagfx.Override(o[0], o[1]))
+		}
+		res, err := flagfx.ParseArgs(fs, tt.args, opts...)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q %q: error %v, want %q", tt.args, tt.overrides, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q %q: %v", tt.args, tt.overrides, err)
+			continue
+		}
+		if *level != tt.level {
+			t.Errorf("%q %q: -log-level = %s, want %s", tt.args, tt.overrides, *level, tt.level)
+		}
+		if got := res.Sources["log-level"]; got != tt.source {
+			t.Errorf("%q %q: source %s, want %s", tt.args, tt.overrides, got, tt.source)
+		}
+	}
+}
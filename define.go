@@ -0,0 +1,167 @@
+package flagfx
+
+import (
+	"encoding"
+	"flag"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Define registers a flag of type T with the flag set and returns a pointer
+// to its value. It dispatches to the matching flag.FlagSet method based on T,
+// which must be one of string, bool, int, int64, uint, uint64, float64 or
+// time.Duration. Define panics for any other type.
+func Define[T any](fs *flag.FlagSet, name string, def T, usage string) *T {
+	p := new(T)
+	switch p := any(p).(type) {
+	case *string:
+		fs.StringVar(p, name, any(def).(string), usage)
+	case *bool:
+		fs.BoolVar(p, name, any(def).(bool), usage)
+	case *int:
+		fs.IntVar(p, name, any(def).(int), usage)
+	case *int64:
+		fs.Int64Var(p, name, any(def).(int64), usage)
+	case *uint:
+		fs.UintVar(p, name, any(def).(uint), usage)
+	case *uint64:
+		fs.Uint64Var(p, name, any(def).(uint64), usage)
+	case *float64:
+		fs.Float64Var(p, name, any(def).(float64), usage)
+	case *time.Duration:
+		fs.DurationVar(p, name, any(def).(time.Duration), usage)
+	default:
+		panic(fmt.Sprintf("flagfx: unsupported type %T for flag -%s", def, name))
+	}
+	recordDefault(fs, name, func() { *p = def })
+	return p
+}
+
+// TextValue is the constraint of DefineText: a type that can be marshaled to
+// and unmarshaled from text, such as *net.IP or *time.Time.
+type TextValue interface {
+	encoding.TextMarshaler
+	encoding.TextUnmarshaler
+}
+
+// DefineText registers a flag with the flag set using flag.FlagSet.TextVar and
+// returns the value it parses into. T must be a pointer type; a new value is
+// allocated for the flag and initialized from def, whose MarshalText form is
+// shown as the default in the usage output.
+//
+//	ip := flagfx.DefineText(fs, "ip", &net.IP{127, 0, 0, 1}, "address to bind")
+func DefineText[T TextValue](fs *flag.FlagSet, name string, def T, usage string) T {
+	t := reflect.TypeFor[T]()
+	if t.Kind() != reflect.Pointer {
+		panic(fmt.Sprintf("flagfx: DefineText requires a pointer type, got %s for flag -%s", t, name))
+	}
+	p := reflect.New(t.Elem()).Interface().(T)
+	fs.TextVar(p, name, def, usage)
+	recordDefault(fs, name, func() {
+		// TextVar succeeded, so the default marshals and unmarshals cleanly.
+		text, _ := def.MarshalText()
+		_ = p.UnmarshalText(text)
+	})
+	return p
+}
+
+// DefineFunc registers a flag with the flag set that calls fn with the value
+// each time the flag is set, like flag.FlagSet.Func, e.g. for flags that
+// register something as a side effect. Flags registered by constructors passed
+// to Provide are parsed inside the barrier, so fn has run for every occurrence
+// of the flag before any dependent constructor runs, and an error returned by
+// fn fails the app with a ParseError. fn is also called for values supplied by
+// fallbacks such as EnvPrefix.
+func DefineFunc(fs *flag.FlagSet, name, usage string, fn func(value string) error) {
+	fs.Func(name, usage, fn)
+}
+
+// DefineDuration registers a time.Duration flag with the flag set and returns a
+// pointer to its value. The default is shown in the usage output in the form
+// accepted on the command line, e.g. 1m30s.
+func DefineDuration(fs *flag.FlagSet, name string, def time.Duration, usage string) *time.Duration {
+	return Define(fs, name, def, usage)
+}
+
+// DefineTime registers a time.Time flag with the flag set, parsed with the given
+// layout, and returns a pointer to its value. The default is shown in the usage
+// output formatted with the layout. A malformed value fails parsing, e.g.
+//
+//	invalid value "today" for flag -since: parsing time "today" as "2006-01-02": ...
+func DefineTime(fs *flag.FlagSet, name string, def time.Time, layout, usage string) *time.Time {
+	t := def
+	fs.Var(&timeValue{t: &t, layout: layout}, name, usage)
+	recordDefault(fs, name, func() { t = def })
+	return &t
+}
+
+// timeValue is a flag.Value for a time.Time in a given layout.
+type timeValue struct {
+	t      *time.Time
+	layout string
+}
+
+// String returns the time formatted with the layout.
+func (v *timeValue) String() string {
+	if v == nil || v.t == nil {
+		return ""
+	}
+	return v.t.Format(v.layout)
+}
+
+// Set parses the time with the layout.
+func (v *timeValue) Set(value string) error {
+	t, err := time.Parse(v.layout, value)
+	if err != nil {
+		return err
+	}
+	*v.t = t
+	return nil
+}
+
+// DefineSince registers a time.Time flag with the flag set that is given
+// relative to now, and returns a pointer to its value. The flag accepts a
+// duration, such as 1h for an hour ago, or an absolute time in RFC 3339
+// format. It defaults to def before now, and the default is shown in the usage
+// output as the duration.
+//
+//	flagfx.Provide(func(fs *flag.FlagSet, now flagfx.Now) *flags {
+//		f.Since = flagfx.DefineSince(fs, "since", 24*time.Hour, now, "show entries since")
+//		...
+//	})
+func DefineSince(fs *flag.FlagSet, name string, def time.Duration, now Now, usage string) *time.Time {
+	v := &sinceValue{t: new(time.Time), now: now}
+	v.Set(def.String())
+	fs.Var(v, name, usage)
+	recordDefault(fs, name, func() { v.Set(def.String()) })
+	return v.t
+}
+
+// sinceValue is a flag.Value for a time.Time given relative to now.
+type sinceValue struct {
+	t    *time.Time
+	now  Now
+	text string // The value as given.
+}
+
+// String returns the value as given.
+func (v *sinceValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.text
+}
+
+// Set parses a duration before now, or an absolute time.
+func (v *sinceValue) Set(value string) error {
+	if d, err := time.ParseDuration(value); err == nil {
+		*v.t = v.now().Add(-d)
+	} else if t, err := time.Parse(time.RFC3339, value); err == nil {
+		*v.t = t
+	} else {
+		return fmt.Errorf("must be a duration such as 1h or a time such as %s", time.RFC3339)
+	}
+	v.text = value
+	return nil
+}
@@ -0,0 +1,292 @@
+package flagfx_test
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestDefine(t *testing.T) {
+	fs := newTestFlagSet()
+	s := flagfx.Define(fs, "s", "def", "")
+	b := flagfx.Define(fs, "b", false, "")
+	i := flagfx.Define(fs, "i", 1, "")
+	i64 := flagfx.Define(fs, "i64", int64(1), "")
+	u := flagfx.Define(fs, "u", uint(1), "")
+	u64 := flagfx.Define(fs, "u64", uint64(1), "")
+	f := flagfx.Define(fs, "f", 1.5, "")
+	d := flagfx.Define(fs, "d", time.Second, "")
+	unset := flagfx.Define(fs, "unset", "default", "")
+
+	args := []string{"-s=x", "-b", "-i=-2", "-i64=-3", "-u=4", "-u64=5", "-f=6.5", "-d=1m"}
+	if _, err := flagfx.ParseArgs(fs, args); err != nil {
+		t.Fatal(err)
+	}
+	if *s != "x" || !*b || *i != -2 || *i64 != -3 || *u != 4 || *u64 != 5 || *f != 6.5 || *d != time.Minute {
+		t.Errorf("values %q %v %d %d %d %d %v %v", *s, *b, *i, *i64, *u, *u64, *f, *d)
+	}
+	if *unset != "default" {
+		t.Errorf("-unset = %q, want its default", *unset)
+	}
+}
+
+func TestDefineBadValue(t *testing.T) {
+	fs := newTestFlagSet()
+	flagfx.Define(fs, "u", uint(1), "")
+	if _, err := flagfx.ParseArgs(fs, []string{"-u=-1"}); err == nil {
+		t.Error("-u=-1: no error")
+	}
+}
+
+func TestDefineUnsupported(t *testing.T) {
+	defer func() {
+		msg, _ := recover().(string)
+		if !strings.Contains(msg, "unsupported type int32 for flag -n") {
+			t.Errorf("panic %q, want the type and flag named", msg)
+		}
+	}()
+	flagfx.Define(newTestFlagSet(), "n", int32(1), "")
+}
+
+func TestDefineDuration(t *testing.T) {
+	fs := newTestFlagSet()
+	d := flagfx.DefineDuration(fs, "timeout", 90*time.Second, "")
+	if got := fs.Lookup("timeout").DefValue; got != "1m30s" {
+		t.Errorf("default shown as %q, want 1m30s", got)
+	}
+	if _, err := flagfx.ParseArgs(fs, []string{"-timeout=2h5m"}); err != nil {
+		t.Fatal(err)
+	}
+	if *d != 2*time.Hour+5*time.Minute || fs.Lookup("timeout").Value.String() != "2h5m0s" {
+		t.Errorf("-timeout = %v", *d)
+	}
+
+	fs = newTestFlagSet()
+	flagfx.DefineDuration(fs, "timeout", 0, "")
+	if _, err := flagfx.ParseArgs(fs, []string{"-timeout=soon"}); err == nil {
+		t.Error("-timeout=soon: no error")
+	}
+}
+
+func TestDefineTime(t *testing.T) {
+	const layout = "2006-01-02"
+	def := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	fs := newTestFlagSet()
+	since := flagfx.DefineTime(fs, "since", def, layout, "")
+	if got := fs.Lookup("since").DefValue; got != "2024-01-02" {
+		t.Errorf("default shown as %q", got)
+	}
+	if _, err := flagfx.ParseArgs(fs, []string{"-since=2025-03-04"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC); !since.Equal(want) {
+		t.Errorf("-since = %v, want %v", *since, want)
+	}
+	if got := fs.Lookup("since").Value.String(); got != "2025-03-04" {
+		t.Errorf("String() = %q, want the value in the layout", got)
+	}
+
+	fs = newTestFlagSet()
+	flagfx.DefineTime(fs, "since", def, layout, "")
+	_, err := flagfx.ParseArgs(fs, []string{"-since=today"})
+	want := `invalid value "today" for flag -since: parsing time "today" as "2006-01-02"`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("error %v, want %q", err, want)
+	}
+}
+
+type features []string
+
+func TestDefineFunc(t *testing.T) {
+	var seen []string // Features seen by a dependent of the flag.
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), []string{"-enable=a", "-enable=b", "-enable=a"}),
+		flagfx.Provide(func(fs *flag.FlagSet) *features {
+			var f features
+			flagfx.DefineFunc(fs, "enable", "", func(value string) error {
+				f = append(f, value)
+				return nil
+			})
+			return &f
+		}),
+		fx.Invoke(func(f *features) { seen = slices.Clone(*f) }),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "a"}; !slices.Equal(seen, want) {
+		t.Errorf("features = %q, want %q", seen, want)
+	}
+}
+
+func TestDefineFuncError(t *testing.T) {
+	var calls int
+	invoked := false
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), []string{"-enable=a", "-enable=bad", "-enable=b"}),
+		flagfx.Provide(func(fs *flag.FlagSet) *features {
+			flagfx.DefineFunc(fs, "enable", "", func(value string) error {
+				calls++
+				if value == "bad" {
+					return errors.New("unknown feature")
+				}
+				return nil
+			})
+			return new(features)
+		}),
+		fx.Invoke(func(*features) { invoked = true }),
+	)
+	var perr flagfx.ParseError
+	if err := app.Err(); !errors.As(err, &perr) || !strings.Contains(err.Error(), `invalid value "bad" for flag -enable: unknown feature`) {
+		t.Fatalf("error %v, want a ParseError for -enable=bad", app.Err())
+	}
+	if calls != 2 {
+		t.Errorf("fn called %d times, want parsing to stop at the failing value", calls)
+	}
+	if invoked {
+		t.Error("dependent invoked despite the parse error")
+	}
+}
+
+// level is a custom encoding.TextUnmarshaler for DefineText.
+type level int
+
+func (l *level) MarshalText() ([]byte, error) {
+	return []byte([]string{"debug", "info", "warn"}[*l]), nil
+}
+
+func (l *level) UnmarshalText(text []byte) error {
+	i := slices.Index([]string{"debug", "info", "warn"}, string(text))
+	if i < 0 {
+		return fmt.Errorf("unknown level %q", text)
+	}
+	*l = level(i)
+	return nil
+}
+
+func TestDefineText(t *testing.T) {
+	tests := []struct {
+		args  []string
+		ip    string
+		level level
+	}{
+		{nil, "127.0.0.1", 1},
+		{[]string{"-ip=10.0.0.1", "-level=warn"}, "10.0.0.1", 2},
+		{[]string{"-ip=::1", "-level=debug"}, "::1", 0},
+	}
+	def := level(1)
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		ip := flagfx.DefineText(fs, "ip", &net.IP{127, 0, 0, 1}, "address to bind")
+		lvl := flagfx.DefineText(fs, "level", &def, "log level")
+		if _, err := flagfx.ParseArgs(fs, tt.args); err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if ip.String() != tt.ip || *lvl != tt.level {
+			t.Errorf("%q: -ip=%s -level=%d, want %s and %d", tt.args, ip, *lvl, tt.ip, tt.level)
+		}
+		if got := fs.Lookup("level").DefValue; got != "info" {
+			t.Errorf("%q: default of -level = %q, want info", tt.args, got)
+		}
+	}
+	if def != 1 {
+		t.Errorf("default changed to %d by parsing", def)
+	}
+}
+
+func TestDefineTextBadValue(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"-ip=localhost", `invalid value "localhost" for flag -ip`},
+		{"-level=trace", `invalid value "trace" for flag -level: unknown level "trace"`},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		flagfx.DefineText(fs, "ip", &net.IP{127, 0, 0, 1}, "")
+		flagfx.DefineText(fs, "level", new(level), "")
+		_, err := flagfx.ParseArgs(fs, []string{tt.arg})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error %v, want %q", tt.arg, err, tt.want)
+		}
+	}
+}
+
+func TestDefineSince(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		args []string
+		want time.Time
+		err  string
+	}{
+		{nil, now.Add(-24 * time.Hour), ""},
+		{[]string{"-since=1h"}, now.Add(-time.Hour), ""},
+		{[]string{"-since=0s"}, now, ""},
+		{[]string{"-since=-30m"}, now.Add(30 * time.Minute), ""},
+		{[]string{"-since=2024-01-02T03:04:05Z"}, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), ""},
+		{[]string{"-since=yesterday"}, time.Time{}, "must be a duration such as 1h or a time such as " + time.RFC3339},
+		{[]string{"-since=2024-01-02"}, time.Time{}, "must be a duration"},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		since := flagfx.DefineSince(fs, "since", 24*time.Hour, func() time.Time { return now }, "")
+		err := fs.Parse(tt.args)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: error %v, want %q", tt.args, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if !since.Equal(tt.want) {
+			t.Errorf("%q: -since = %s, want %s", tt.args, since, tt.want)
+		}
+	}
+}
+
+func TestDefineSinceClock(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	var since *time.Time
+	fs := newTestFlagSet()
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(fs, []string{"-since=2h"}),
+		flagfx.Clock(func() time.Time { return now }),
+		flagfx.Provide(func(fs *flag.FlagSet, now flagfx.Now) *time.Time {
+			return flagfx.DefineSince(fs, "since", 24*time.Hour, now, "")
+		}),
+		fx.Populate(&since),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := now.Add(-2 * time.Hour); !since.Equal(want) {
+		t.Errorf("-since = %s, want %s", since, want)
+	}
+	if got := fs.Lookup("since").DefValue; got != "24h0m0s" {
+		t.Errorf("default %q, want the duration", got)
+	}
+	if err := flagfx.Reparse(fs, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := now.Add(-24 * time.Hour); !since.Equal(want) {
+		t.Errorf("-since = %s after Reparse, want %s", since, want)
+	}
+}
@@ -0,0 +1,191 @@
+package flagfx
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"go.uber.org/fx"
+)
+
+// warningOutput is the destination for warnings, such as the use of a
+// deprecated flag.
+type warningOutput struct {
+	w io.Writer
+}
+
+// defaultWarningOutput provides the default warning output, which is os.Stderr.
+// This can be replaced using the WarningOutput option.
+func defaultWarningOutput() warningOutput {
+	return warningOutput{w: os.Stderr}
+}
+
+// WarningOutput allows replacing the default destination for warnings
+// (os.Stderr), for example to capture them in tests.
+func WarningOutput(w io.Writer) fx.Option {
+	return fx.Replace(warningOutput{w: w})
+}
+
+// WarningsAsErrors returns an fx.Option that fails the app if any warning was
+// emitted while parsing, such as the use of a flag passed to Deprecated or a
+// flag shadowing an environment variable with WarnOnShadow, to tighten a tool
+// for production pipelines. Instead of being written, the warnings are joined
+// into one error, with one line per warning naming the flag and the reason,
+// which is returned once the barrier's checks have run.
+func WarningsAsErrors() Option {
+	return addHook(stageSetup, func(p *parser) error {
+		p.warningsAsErrors = true
+		return nil
+	})
+}
+
+// Deprecated returns an fx.Option that keeps supporting a renamed flag.
+// It registers the old name as an alias whose value is copied to the new flag,
+// and writes a warning such as
+//
+//	flagfx: flag -loglevel is deprecated, use -log-level
+//
+// to the warning output whenever the old name is actually used. The alias is
+// hidden like a flag passed to Hidden, so only the new name is advertised.
+// The new flag must be defined; otherwise it is a configuration error.
+func Deprecated(old, new string) Option {
+	return DeprecatedUntil(old, new, "")
+}
+
+// DeprecatedUntil is like Deprecated, but stages the removal of the old name
+// in the given version of the app. Before it, using the old name warns that it
+// will be removed:
+//
+//	flagfx: flag -loglevel is deprecated and will be removed in v2.0.0, use -log-level
+//
+// Once the Version set with AppVersion is removeIn or later, using the old
+// name fails the app instead:
+//
+//	flagfx: flag -loglevel was removed in v2.0.0, use -log-level
+//
+// Without a Version, or with one that is not a number such as "unknown", the
+// old name keeps warning.
+func DeprecatedUntil(old, new, removeIn string) Option {
+	d := deprecation{old: old, new: new}
+	removed := func(p *parser) bool {
+		return removeIn != "" && p.version.atLeast(removeIn)
+	}
+	return options(
+		addHook(stageSetup, func(p *parser) error {
+			if p.fs.Lookup(new) == nil {
+				return fmt.Errorf("flagfx: flag -%s replacing deprecated -%s is not defined", new, old)
+			}
+			value := aliasValue{
+				fs:     p.fs,
+				target: new,
+				onSet: func() {
+					// The uses are recorded by the parser, so that every
+					// parse starts afresh.
+					if !slices.Contains(p.deprecated, d) {
+						p.deprecated = append(p.deprecated, d)
+					}
+					if removed(p) {
+						return // Reported once the flags are parsed.
+					}
+					if removeIn == "" {
+						p.warnf("flag -%s is deprecated, use -%s", old, new)
+					} else {
+						p.warnf("flag -%s is deprecated and will be removed in %s, use -%s", old, removeIn, new)
+					}
+				},
+			}
+			optionVar(p.fs, old, fmt.Sprintf("deprecated, use -%s", new), value)
+			p.hide(old)
+			return nil
+		}),
+		addHook(stageParsed, func(p *parser) error {
+			if slices.Contains(p.deprecated, d) && removed(p) {
+				return fmt.Errorf("flagfx: flag -%s was removed in %s, use -%s", old, removeIn, new)
+			}
+			return nil
+		}),
+	)
+}
+
+// OnDeprecated returns an fx.Option that calls fn for every flag passed to
+// Deprecated or DeprecatedUntil that was actually used, with its old and new
+// names, e.g. to count the use of deprecated flags across a fleet. It
+// complements the warning, which is written as usual, and also reports the
+// flags past their removal. fn is called once per flag, within the barrier,
+// once the values of all flags have been applied, in the order the flags were
+// first used. A panic in fn is recovered and fails the app with an error.
+func OnDeprecated(fn func(old, new string)) Option {
+	return addHook(stageSetup, func(p *parser) error {
+		p.onDeprecated = append(p.onDeprecated, fn)
+		return nil
+	})
+}
+
+// deprecation records the use of a deprecated flag.
+type deprecation struct {
+	old, new string
+}
+
+// notifyDeprecated calls the callbacks registered with OnDeprecated for the
+// deprecated flags that were used.
+func (p *parser) notifyDeprecated() error {
+	for _, fn := range p.onDeprecated {
+		for _, d := range p.deprecated {
+			if err := callDeprecated(fn, d); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// callDeprecated calls fn for the deprecation, recovering a panic into an
+// error.
+func callDeprecated(fn func(old, new string), d deprecation) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("flagfx: OnDeprecated callback for flag -%s panicked: %v", d.old, r)
+		}
+	}()
+	fn(d.old, d.new)
+	return nil
+}
+
+// aliasValue is a flag.Value that forwards to the value of another flag.
+type aliasValue struct {
+	fs     *flag.FlagSet
+	target string
+	onSet  func() // Called after the value has been set, if not nil.
+}
+
+// String returns the value of the target flag.
+func (v *aliasValue) String() string {
+	// The flag package may call String on a zero value.
+	if v == nil || v.fs == nil {
+		return ""
+	}
+	return v.fs.Lookup(v.target).Value.String()
+}
+
+// Set sets the value of the target flag.
+func (v *aliasValue) Set(s string) error {
+	if err := v.fs.Set(v.target, s); err != nil {
+		return err
+	}
+	if v.onSet != nil {
+		v.onSet()
+	}
+	return nil
+}
+
+// IsBoolFlag reports whether the target is a boolean flag, so that an alias of
+// a boolean flag can be given without a value.
+func (v *aliasValue) IsBoolFlag() bool {
+	if v == nil || v.fs == nil {
+		return false
+	}
+	bf, ok := v.fs.Lookup(v.target).Value.(interface{ IsBoolFlag() bool })
+	return ok && bf.IsBoolFlag()
+}
@@ -0,0 +1,155 @@
+package flagfx_test
+
+import (
+	"strings"
+	"testing"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestDeprecatedUntil(t *testing.T) {
+	const (
+		warning = "flagfx: flag -loglevel is deprecated and will be removed in v2.0.0, use -log-level\n"
+		removed = "flagfx: flag -loglevel was removed in v2.0.0, use -log-level"
+	)
+	tests := []struct {
+		version string
+		args    []string
+		warning string
+		err     string
+	}{
+		{"", []string{"-loglevel=debug"}, warning, ""},
+		{"unknown", []string{"-loglevel=debug"}, warning, ""},
+		{"v1.9.3", []string{"-loglevel=debug"}, warning, ""},
+		{"v2.0.0-rc.1", []string{"-loglevel=debug"}, warning, ""},
+		{"v2.0.0", []string{"-loglevel=debug"}, "", removed},
+		{"2.0", []string{"-loglevel=debug"}, "", removed},
+		{"v2.1.0", []string{"-loglevel=debug"}, "", removed},
+		{"v1.9.3", []string{"-log-level=debug"}, "", ""},
+		{"v2.1.0", []string{"-log-level=debug"}, "", ""},
+	}
+	for _, tt := range tests {
+		var warnings strings.Builder
+		fs := newTestFlagSet()
+		level := fs.String("log-level", "info", "")
+		app := fx.New(
+			fx.NopLogger,
+			flagfx.ModuleFor(fs, tt.args),
+			flagfx.AppVersion(tt.version),
+			flagfx.WarningOutput(&warnings),
+			flagfx.DeprecatedUntil("loglevel", "log-level", "v2.0.0"),
+			fx.Invoke(func(flagfx.Ready) {}),
+		)
+		err := app.Err()
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s %q: error %v, want %q", tt.version, tt.args, err, tt.err)
+			}
+		} else if err != nil {
+			t.Errorf("%s %q: %v", tt.version, tt.args, err)
+		} else if *level != "debug" {
+			t.Errorf("%s %q: -log-level = %s, want debug", tt.version, tt.args, *level)
+		}
+		if got := warnings.String(); got != tt.warning {
+			t.Errorf("%s %q: warnings %q, want %q", tt.version, tt.args, got, tt.warning)
+		}
+	}
+}
+
+func TestOnDeprecated(t *testing.T) {
+	var used []string
+	fs := newTestFlagSet()
+	fs.String("log-level", "info", "")
+	fs.String("log-file", "", "")
+	fs.Bool("verbose", false, "")
+	_, err := flagfx.ParseArgs(fs, []string{"-logfile=x", "-loglevel=debug", "-logfile=y", "-verbose"},
+		flagfx.Deprecated("loglevel", "log-level"),
+		flagfx.Deprecated("logfile", "log-file"),
+		flagfx.Deprecated("v", "verbose"),
+		flagfx.OnDeprecated(func(old, new string) { used = append(used, old+"->"+new) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each used flag is reported once, in the order of first use; -v is not
+	// reported, as -verbose was used instead.
+	if want := []string{"logfile->log-file", "loglevel->log-level"}; !slicesEqual(used, want) {
+		t.Errorf("OnDeprecated called with %q, want %q", used, want)
+	}
+}
+
+func TestOnDeprecatedPanic(t *testing.T) {
+	fs := newTestFlagSet()
+	fs.String("log-level", "info", "")
+	_, err := flagfx.ParseArgs(fs, []string{"-loglevel=debug"},
+		flagfx.Deprecated("loglevel", "log-level"),
+		flagfx.OnDeprecated(func(old, new string) { panic("counter unavailable") }),
+	)
+	if want := "flagfx: OnDeprecated callback for flag -loglevel panicked: counter unavailable"; err == nil || err.Error() != want {
+		t.Errorf("error %v, want %q", err, want)
+	}
+}
+
+func TestWarningsAsErrors(t *testing.T) {
+	const (
+		deprecated = "flagfx: flag -loglevel is deprecated, use -log-level"
+		shadowed   = "flagfx: flag -port is set on the command line, which takes precedence over environment variable APP_PORT"
+	)
+	tests := []struct {
+		args  []string
+		fatal bool
+		want  []string
+	}{
+		{[]string{"-log-level=debug"}, true, nil},
+		{[]string{"-loglevel=debug"}, false, []string{deprecated}},
+		{[]string{"-loglevel=debug"}, true, []string{deprecated}},
+		{[]string{"-port=1"}, true, []string{shadowed}},
+		{[]string{"-loglevel=debug", "-port=1"}, false, []string{deprecated, shadowed}},
+		{[]string{"-loglevel=debug", "-port=1"}, true, []string{deprecated, shadowed}},
+	}
+	for _, tt := range tests {
+		var warnings strings.Builder
+		fs := newTestFlagSet()
+		fs.String("log-level", "info", "")
+		fs.Int("port", 80, "")
+		opts := []fx.Option{
+			fx.NopLogger,
+			flagfx.ModuleFor(fs, tt.args),
+			flagfx.Env(func(key string) (string, bool) { return "8080", key == "APP_PORT" }),
+			flagfx.WarningOutput(&warnings),
+			flagfx.EnvPrefix("app"),
+			flagfx.WarnOnShadow(nil),
+			flagfx.Deprecated("loglevel", "log-level"),
+			fx.Invoke(func(flagfx.Ready) {}),
+		}
+		if tt.fatal {
+			opts = append(opts, flagfx.WarningsAsErrors())
+		}
+		err := fx.New(opts...).Err()
+		if !tt.fatal || tt.want == nil {
+			if err != nil {
+				t.Errorf("%q: %v", tt.args, err)
+			}
+			var want string
+			for _, line := range tt.want {
+				want += line + "\n"
+			}
+			if got := warnings.String(); got != want {
+				t.Errorf("%q: warnings %q, want %q", tt.args, got, want)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("%q: got no error, want %q", tt.args, tt.want)
+			continue
+		}
+		if !strings.Contains(err.Error(), strings.Join(tt.want, "\n")) {
+			t.Errorf("%q: error %q, want one line for each of %q", tt.args, err, tt.want)
+		}
+		if got := warnings.String(); got != "" {
+			t.Errorf("%q: warnings %q written, want none", tt.args, got)
+		}
+	}
+}
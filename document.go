@@ -0,0 +1,125 @@
+package flagfx
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigJSON returns an fx.Option that loads default flag values from a JSON
+// document. For each registered flag, keyFor returns the path of its value in
+// the document, where dots separate the keys of nested objects, so
+// "server.port" refers to {"server": {"port": 8080}}. A nil keyFor uses the
+// flag name as a top-level key, dots included. Flags missing from the
+// document, or null in it, are left alone, as are keys naming no flag.
+//
+// Numbers and booleans are passed to the flag in their JSON form, and every
+// element of an array is set in turn, for repeatable flags such as
+// DefineSlice. Like ConfigFile, values only apply to flags that are still
+// unset.
+func ConfigJSON(path string, keyFor func(flagName string) string) Option {
+	return ConfigDocument(path, unmarshalJSON, keyFor)
+}
+
+// unmarshalJSON decodes a JSON document, keeping numbers as json.Number so
+// they are passed to flags verbatim.
+func unmarshalJSON(data []byte, v any) error {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	return d.Decode(v)
+}
+
+// ConfigDocument is like ConfigJSON, but decodes the document with unmarshal,
+// such as yaml.Unmarshal, to support other formats with the same structure.
+// unmarshal is called with a pointer to an any value and should decode
+// objects into map[string]any.
+func ConfigDocument(path string, unmarshal func(data []byte, v any) error, keyFor func(flagName string) string) Option {
+	return addHook(stageFile, func(p *parser) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("flagfx: read config file: %w", err)
+		}
+		var doc any
+		if err := unmarshal(data, &doc); err != nil {
+			return fmt.Errorf("flagfx: parse config file %s: %w", path, err)
+		}
+		var errs error
+		p.fs.VisitAll(func(f *flag.Flag) {
+			if errs != nil || p.set[f.Name] {
+				return
+			}
+			value, ok := documentValue(doc, f.Name, keyFor)
+			if !ok {
+				return
+			}
+			if err := p.setDocumentValue(f.Name, value); err != nil {
+				errs = fmt.Errorf("flagfx: %s: %w", path, err)
+			}
+		})
+		return errs
+	})
+}
+
+// documentValue looks up the value of the named flag in the document.
+func documentValue(doc any, name string, keyFor func(flagName string) string) (any, bool) {
+	keys := []string{name}
+	if keyFor != nil {
+		keys = strings.Split(keyFor(name), ".")
+	}
+	v := doc
+	for _, key := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return v, v != nil
+}
+
+// setDocumentValue sets the named flag to a value from a document, setting
+// each element of an array in turn.
+func (p *parser) setDocumentValue(name string, value any) error {
+	values, ok := value.([]any)
+	if !ok {
+		values = []any{value}
+	}
+	for _, v := range values {
+		s, err := documentString(v)
+		if err != nil {
+			return fmt.Errorf("invalid value for flag -%s: %v", name, err)
+		}
+		if err := p.setFallback(name, s, SourceConfig); err != nil {
+			return fmt.Errorf("invalid value %q for flag -%s: %v", s, name, err)
+		}
+	}
+	return nil
+}
+
+// documentString converts a scalar value from a document to the form
+// accepted by flag.Value.Set.
+func documentString(v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case json.Number:
+		return v.String(), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case int, int64, uint64:
+		return fmt.Sprint(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	default:
+		return "", fmt.Errorf("expected a string, number, boolean or array, got %T", v)
+	}
+}
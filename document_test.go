@@ -0,0 +1,91 @@
+package flagfx_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestConfigJSON(t *testing.T) {
+	fs := newTestFlagSet()
+	port := fs.Int("port", 80, "")
+	host := fs.String("host", "localhost", "")
+	debug := fs.Bool("debug", false, "")
+	ratio := fs.Float64("ratio", 0, "")
+	id := fs.Uint64("id", 0, "")
+	timeout := fs.Duration("timeout", time.Second, "")
+	tags := flagfx.DefineSlice(fs, "tags", "")
+	addr := fs.String("server.addr", "", "")
+	workers := fs.Int("workers", 4, "")
+	name := fs.String("name", "app", "")
+	path := writeConfig(t, `{
+		"port": 8080,
+		"host": "example.com",
+		"debug": true,
+		"ratio": 0.25,
+		"id": 18446744073709551615,
+		"timeout": "1m30s",
+		"tags": ["a", "b"],
+		"server": {"addr": ":9090"},
+		"workers": null,
+		"unknown": 1
+	}`)
+	_, err := flagfx.ParseArgs(fs, []string{"-name=cli", "-host=cli.example.com"},
+		flagfx.ConfigJSON(path, func(name string) string { return name }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *port != 8080 || *debug != true || *ratio != 0.25 || *id != 18446744073709551615 || *timeout != 90*time.Second {
+		t.Errorf("port=%d debug=%t ratio=%g id=%d timeout=%s, want the JSON values", *port, *debug, *ratio, *id, *timeout)
+	}
+	if !slicesEqual(*tags, []string{"a", "b"}) {
+		t.Errorf("tags = %q, want [a b]", *tags)
+	}
+	if *addr != ":9090" {
+		t.Errorf("server.addr = %q, want the nested value", *addr)
+	}
+	if *workers != 4 {
+		t.Errorf("workers = %d, want the default for null", *workers)
+	}
+	if *host != "cli.example.com" || *name != "cli" {
+		t.Errorf("host=%s name=%s, want the command line to take precedence", *host, *name)
+	}
+}
+
+func TestConfigJSONTopLevelKeys(t *testing.T) {
+	fs := newTestFlagSet()
+	addr := fs.String("server.addr", "", "")
+	path := writeConfig(t, `{"server.addr": ":8080", "server": {"addr": ":9090"}}`)
+	if _, err := flagfx.ParseArgs(fs, nil, flagfx.ConfigJSON(path, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if *addr != ":8080" {
+		t.Errorf("server.addr = %q, want the dotted top-level key", *addr)
+	}
+}
+
+func TestConfigJSONErrors(t *testing.T) {
+	tests := []struct {
+		doc string
+		err string
+	}{
+		{`{"port": 80.5}`, `invalid value "80.5" for flag -port`},
+		{`{"port": "http"}`, `invalid value "http" for flag -port`},
+		{`{"debug": "maybe"}`, `invalid value "maybe" for flag -debug`},
+		{`{"port": {"value": 1}}`, "invalid value for flag -port: expected a string, number, boolean or array, got map[string]interface {}"},
+		{`{"port": [1, [2]]}`, "invalid value for flag -port"},
+		{`{"port": 1,}`, "parse config file"},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		fs.Int("port", 80, "")
+		fs.Bool("debug", false, "")
+		_, err := flagfx.ParseArgs(fs, nil, flagfx.ConfigJSON(writeConfig(t, tt.doc), nil))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error %v, want %q", tt.doc, err, tt.err)
+		}
+	}
+}
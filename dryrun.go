@@ -0,0 +1,51 @@
+package flagfx
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+)
+
+// dryRunFlag is the name of the flag registered by DryRun.
+const dryRunFlag = "flagfx-dry-run"
+
+// DryRun returns an fx.Option that registers a hidden -flagfx-dry-run flag,
+// which makes the app print the resolved flags and exit cleanly with code 0
+// through the Exiter, without running anything that depends on the flags.
+// This confirms that the configuration resolves and validates, e.g. in CI.
+//
+// The flags are printed to stdout once every layer and check of the barrier
+// has run, including Required, Validate and AfterParse, one per line in
+// sorted order with the source of the value, see Sources:
+//
+//	addr=:8080 (default)
+//	log-level=debug (env)
+//
+// The value extends from the first "=" to the last " (". Flags passed to
+// Hidden are left out, unless ShowHidden is used. With EmitJSON, the flags are
+// only written as JSON.
+func DryRun() Option {
+	return addHook(stageSetup, func(p *parser) error {
+		p.dryRun = (*bool)(optionVar(p.fs, dryRunFlag, "print the resolved flags and exit", optionBool(false)))
+		p.hide(dryRunFlag)
+		return nil
+	})
+}
+
+// printDryRun prints the resolved flags and exits if -flagfx-dry-run is set.
+func (p *parser) printDryRun() {
+	if p.dryRun == nil || !*p.dryRun {
+		return
+	}
+	if len(p.emitJSON) > 0 {
+		// The flags have been written as JSON instead.
+		p.exit(0)
+		return
+	}
+	values, sources := newValues(p.fs, p.isHidden), p.newSources()
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		fmt.Fprintf(os.Stdout, "%s=%s (%s)\n", name, values[name], sources[name])
+	}
+	p.exit(0)
+}
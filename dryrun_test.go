@@ -0,0 +1,61 @@
+package flagfx_test
+
+import (
+	"strings"
+	"testing"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestDryRun(t *testing.T) {
+	tests := []struct {
+		args []string
+		out  string
+		code int // -1 if the app must not exit.
+		err  string
+	}{
+		{[]string{"-port=1"}, "", -1, ""},
+		{[]string{"-flagfx-dry-run", "-port=1"}, "" +
+			"host=example.com (env)\n" +
+			"port=1 (command line)\n" +
+			"workers=4 (default)\n", 0, ""},
+		{[]string{"-flagfx-dry-run"}, "", -1, "-port"},
+	}
+	for _, tt := range tests {
+		code := -1
+		fs := newTestFlagSet()
+		fs.Int("port", 0, "")
+		fs.String("host", "localhost", "")
+		fs.Int("workers", 4, "")
+		fs.Bool("debug", false, "")
+		var err error
+		out := captureStdout(t, func() {
+			err = fx.New(
+				fx.NopLogger,
+				flagfx.ModuleFor(fs, tt.args),
+				flagfx.Env(func(key string) (string, bool) { return "example.com", key == "APP_HOST" }),
+				flagfx.Exit(func(c int) { code = c }),
+				flagfx.EnvPrefix("app"),
+				flagfx.Hidden("debug"),
+				flagfx.Required("port"),
+				flagfx.DryRun(),
+				fx.Invoke(func(flagfx.Ready) {}),
+			).Err()
+		})
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: error %v, want %q", tt.args, err, tt.err)
+			}
+		} else if err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+		}
+		if string(out) != tt.out {
+			t.Errorf("%q: output %q, want %q", tt.args, out, tt.out)
+		}
+		if code != tt.code {
+			t.Errorf("%q: exit code %d, want %d", tt.args, code, tt.code)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package flagfx
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Dump returns an fx.Option that writes a table of every flag's name, current
+// value, default and usage to w once parsing has completed, for debugging the
+// startup configuration. A nil w writes to os.Stderr. Flags passed to Hidden
+// are left out, unless ShowHidden is used.
+func Dump(w io.Writer) Option {
+	if w == nil {
+		w = os.Stderr
+	}
+	return addHook(stageParsed, func(p *parser) error {
+		return dump(w, p.fs, p.isHidden)
+	})
+}
+
+// dump writes the table of flags to w, leaving out the flags for which hidden
+// reports true.
+func dump(w io.Writer, fs *flag.FlagSet, hidden func(name string) bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tVALUE\tDEFAULT\tUSAGE")
+	fs.VisitAll(func(f *flag.Flag) {
+		if hidden(f.Name) {
+			return
+		}
+		fmt.Fprintf(tw, "-%s\t%s\t%s\t%s\n", f.Name, f.Value, f.DefValue, f.Usage)
+	})
+	return tw.Flush()
+}
+
+// printConfigFlag is the name of the flag registered by PrintConfig.
+const printConfigFlag = "flagfx-print-config"
+
+// PrintConfig returns an fx.Option that registers a -flagfx-print-config flag,
+// which makes the app print the effective configuration and exit cleanly with
+// code 0 through the Exiter, for end users to inspect where each value came
+// from. Unlike DryRun, the flag is listed in the usage output.
+//
+// The flags are printed to stdout as an aligned table, in sorted order, once
+// every layer has been applied, but before the checks such as Required, so
+// that an incomplete configuration can be inspected as well:
+//
+//	NAME        VALUE  DEFAULT  SOURCE
+//	-addr       :8080  :8080    default
+//	-log-level  debug  info     env
+//
+// Flags passed to Hidden are left out, unless ShowHidden is used.
+func PrintConfig() Option {
+	return addHook(stageSetup, func(p *parser) error {
+		p.printConfig = (*bool)(optionVar(p.fs, printConfigFlag, "print the effective configuration and exit", optionBool(false)))
+		return nil
+	})
+}
+
+// printEffective prints the effective configuration and quits if
+// -flagfx-print-config is set.
+func (p *parser) printEffective() error {
+	if p.printConfig == nil || !*p.printConfig {
+		return nil
+	}
+	sources := p.newSources()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tVALUE\tDEFAULT\tSOURCE")
+	p.fs.VisitAll(func(f *flag.Flag) {
+		if p.isHidden(f.Name) || f.Name == printConfigFlag {
+			return
+		}
+		fmt.Fprintf(tw, "-%s\t%s\t%s\t%s\n", f.Name, f.Value, f.DefValue, sources[f.Name])
+	})
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	p.quit()
+	return nil
+}
+
+// EchoCommandLine returns an fx.Option that writes, once parsing has
+// completed, a command line reproducing the resolved configuration to w as a
+// single line, e.g. to paste the exact invocation into a bug report:
+//
+//	-log-level=debug -port=9090 '-token=****' -verbose
+//
+// It lists every flag whose value differs from its default, in
+// lexicographical order, whichever layer supplied the value. Boolean flags
+// that are true are given without a value, and tokens containing characters
+// that are special to a POSIX shell are quoted. The values of secret flags,
+// see DefineSecret, are masked as everywhere else, so the line may need
+// editing before it can be run. Flags passed to Hidden are left out, unless
+// ShowHidden is used.
+func EchoCommandLine(w io.Writer) Option {
+	return addHook(stageParsed, func(p *parser) error {
+		_, err := fmt.Fprintln(w, strings.Join(p.commandLine(), " "))
+		return err
+	})
+}
+
+// commandLine returns the tokens of the flags whose value differs from their
+// default.
+func (p *parser) commandLine() []string {
+	var tokens []string
+	p.fs.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		if p.isHidden(f.Name) || value == f.DefValue {
+			return
+		}
+		token := "-" + f.Name
+		if !isBoolFlag(f) || value != "true" {
+			token += "=" + value
+		}
+		tokens = append(tokens, shellWord(token))
+	})
+	return tokens
+}
+
+// shellWord quotes s for a POSIX shell, unless it consists only of characters
+// that are never special.
+func shellWord(s string) string {
+	safe := func(r rune) bool {
+		return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_.,:/=+@%", r))
+	}
+	for _, r := range s {
+		if !safe(r) {
+			return shellQuote(s)
+		}
+	}
+	return s
+}
+
+// EmitJSON returns an fx.Option that writes the resolved flags to w as a JSON
+// object once the barrier has completed, for CI and GUI tools. The object is
+// keyed by flag name, and values and defaults are rendered as strings, as
+// shown on the command line, whatever the type of the flag:
+//
+//	{"port": {"value": "8080", "default": "80", "usage": "listen port", "set": true, "source": "env"}}
+//
+// set reports whether the flag was set on the command line or by a fallback,
+// and source is the layer that supplied the value, see Sources. A nil w writes
+// to os.Stdout. Flags passed to Hidden are left out, unless ShowHidden is
+// used. Combined with DryRun, the JSON takes the place of the listing of
+// -flagfx-dry-run before the app exits.
+func EmitJSON(w io.Writer) Option {
+	if w == nil {
+		w = os.Stdout
+	}
+	return addHook(stageSetup, func(p *parser) error {
+		p.emitJSON = append(p.emitJSON, w)
+		return nil
+	})
+}
+
+// flagJSON is the JSON form of a flag written by EmitJSON.
+type flagJSON struct {
+	Value   string `json:"value"`
+	Default string `json:"default"`
+	Usage   string `json:"usage"`
+	Set     bool   `json:"set"`
+	Source  string `json:"source"`
+}
+
+// writeJSON writes the resolved flags to the writers registered with EmitJSON.
+func (p *parser) writeJSON() error {
+	if len(p.emitJSON) == 0 {
+		return nil
+	}
+	sources := p.newSources()
+	flags := make(map[string]flagJSON)
+	p.fs.VisitAll(func(f *flag.Flag) {
+		if p.isHidden(f.Name) {
+			return
+		}
+		flags[f.Name] = flagJSON{
+			Value:   f.Value.String(),
+			Default: f.DefValue,
+			Usage:   f.Usage,
+			Set:     p.set[f.Name],
+			Source:  sources[f.Name].String(),
+		}
+	})
+	data, err := json.Marshal(flags)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	for _, w := range p.emitJSON {
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("flagfx: write JSON: %w", err)
+		}
+	}
+	return nil
+}
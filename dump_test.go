@@ -0,0 +1,138 @@
+package flagfx_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"maps"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lftk/flagfx"
+)
+
+// captureStdout returns what fn writes to os.Stdout.
+func captureStdout(t *testing.T, fn func()) []byte {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	fn()
+	w.Close()
+	return <-done
+}
+
+func TestPrintConfig(t *testing.T) {
+	path := writeConfig(t, "log-level=warn\n")
+	fs := newTestFlagSet()
+	fs.String("addr", ":8080", "address to listen on")
+	fs.String("log-level", "info", "log level")
+	fs.Int("workers", 4, "number of workers")
+	fs.String("secret", "", "hidden secret")
+	var err error
+	out := captureStdout(t, func() {
+		_, err = flagfx.ParseArgs(fs, []string{"-flagfx-print-config", "-workers=8"},
+			flagfx.PrintConfig(),
+			flagfx.ConfigFile(path),
+			flagfx.Hidden("secret"),
+			flagfx.Required("secret"), // Skipped, as the app exits first.
+		)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "printconfig.txt", out)
+}
+
+func TestEmitJSON(t *testing.T) {
+	type flagJSON struct {
+		Value   string `json:"value"`
+		Default string `json:"default"`
+		Usage   string `json:"usage"`
+		Set     bool   `json:"set"`
+		Source  string `json:"source"`
+	}
+	var out bytes.Buffer
+	fs := newTestFlagSet()
+	fs.Int("port", 80, "listen port")
+	fs.String("host", "localhost", "listen host")
+	fs.Int("workers", 4, "number of workers")
+	fs.Bool("debug", false, "")
+	flagfx.DefineSecret(fs, "token", "API token")
+	_, err := flagfx.ParseArgs(fs, []string{"-port=8080", "-token=s3cr3t"},
+		flagfx.ConfigFile(writeConfig(t, "host=example.com\n")),
+		flagfx.Hidden("debug"),
+		flagfx.EmitJSON(&out),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "s3cr3t") {
+		t.Errorf("JSON %s reveals the secret", out.String())
+	}
+
+	var got map[string]flagJSON
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("decode %s: %v", out.String(), err)
+	}
+	want := map[string]flagJSON{
+		"port":    {Value: "8080", Default: "80", Usage: "listen port", Set: true, Source: "command line"},
+		"host":    {Value: "example.com", Default: "localhost", Usage: "listen host", Set: true, Source: "config"},
+		"workers": {Value: "4", Default: "4", Usage: "number of workers", Set: false, Source: "default"},
+		"token":   {Value: "****", Default: "", Usage: "API token", Set: true, Source: "command line"},
+	}
+	if !maps.Equal(got, want) {
+		t.Errorf("JSON = %+v, want %+v", got, want)
+	}
+}
+
+func TestEchoCommandLine(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "\n"},
+		{[]string{"-port=80"}, "\n"},
+		{[]string{"-verbose", "-port=9090", "-log-level=debug"}, "-log-level=debug -port=9090 -verbose\n"},
+		{[]string{"-color=false"}, "-color=false\n"},
+		{[]string{"-token=s3cr3t"}, "'-token=****'\n"},
+		{[]string{"-name=a b"}, "'-name=a b'\n"},
+		{[]string{"-name=it's"}, `'-name=it'\''s'` + "\n"},
+		{[]string{"-name=$HOME"}, "'-name=$HOME'\n"},
+		{[]string{"-name=a,b:c/d"}, "-name=a,b:c/d\n"},
+		{[]string{"-name="}, "\n"},
+		{[]string{"-debug"}, "\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		fs := newTestFlagSet()
+		fs.Int("port", 80, "")
+		fs.String("log-level", "info", "")
+		fs.String("name", "", "")
+		fs.Bool("verbose", false, "")
+		fs.Bool("color", true, "")
+		fs.Bool("debug", false, "")
+		flagfx.DefineSecret(fs, "token", "")
+		_, err := flagfx.ParseArgs(fs, tt.args,
+			flagfx.Hidden("debug"),
+			flagfx.EchoCommandLine(&out),
+		)
+		if err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if got := out.String(); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
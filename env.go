@@ -0,0 +1,196 @@
+package flagfx
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"go.uber.org/fx"
+)
+
+// EnvLookup retrieves the value of the environment variable named by the key,
+// reporting whether the variable is present. It has the signature of os.LookupEnv.
+type EnvLookup func(key string) (string, bool)
+
+// defaultEnvLookup provides the default environment lookup, which is os.LookupEnv.
+// This can be replaced using the Env option.
+func defaultEnvLookup() EnvLookup {
+	return os.LookupEnv
+}
+
+// Env allows replacing the default environment lookup (os.LookupEnv),
+// for example to supply a fake environment in tests.
+func Env(fn func(key string) (string, bool)) fx.Option {
+	return fx.Replace(EnvLookup(fn))
+}
+
+// EnvPrefix returns an fx.Option that makes flags fall back to environment
+// variables. For a flag named "log-level" and the prefix "APP", the variable
+// APP_LOG_LEVEL is consulted: the name is uppercased and dashes and dots are
+// replaced by underscores.
+//
+// The flags of a module provided with Prefix are namespaced the same way, as
+// the module prefix is part of their names: the variable joins the app
+// prefix, the module prefix and the flag name with underscores, so the flags
+// -access.log-level and -error.log-level of two instances of a logging module
+// read APP_ACCESS_LOG_LEVEL and APP_ERROR_LOG_LEVEL.
+//
+// The precedence is command line > environment > flag default: the variable
+// is only applied to flags that were not set on the command line. Two flags
+// mapping to the same variable, such as -access.log-level and
+// -access-log-level, are a configuration error.
+//
+// The usage output advertises the variable backing each flag, appending e.g.
+// (env: APP_LOG_LEVEL) to its help text, see EnvUsage.
+func EnvPrefix(prefix string) Option {
+	return options(
+		addHook(stageSetup, func(p *parser) error {
+			p.envPrefixes = append(p.envPrefixes, prefix)
+			return nil
+		}),
+		addHook(stageEnv, func(p *parser) error {
+			if err := checkEnvNames(p.fs, prefix); err != nil {
+				return err
+			}
+			var err error
+			p.fs.VisitAll(func(f *flag.Flag) {
+				if err != nil {
+					return
+				}
+				key := envName(prefix, f.Name)
+				if p.set[f.Name] {
+					if _, ok := p.env(key); ok && p.cli[f.Name] && p.shadow != nil {
+						p.warnTo(p.shadow, "flag -%s is set on the command line, "+
+							"which takes precedence over environment variable %s", f.Name, key)
+					}
+					return
+				}
+				value, ok := p.env(key)
+				if !ok {
+					return
+				}
+				if serr := p.setFallback(f.Name, value, SourceEnv); serr != nil {
+					err = fmt.Errorf("flagfx: invalid value %q for flag -%s from environment variable %s: %v",
+						value, f.Name, key, serr)
+				}
+			})
+			return err
+		}),
+	)
+}
+
+// WarnOnShadow returns an fx.Option that writes a warning to w for each flag
+// set on the command line while the environment variable backing it, see
+// EnvPrefix, is set as well, e.g.
+//
+//	flagfx: flag -log-level is set on the command line, which takes precedence over environment variable APP_LOG_LEVEL
+//
+// This explains why a variable seems to be ignored. A nil w writes to the
+// warning output, see WarningOutput. Values are never included, as they may be
+// secrets.
+func WarnOnShadow(w io.Writer) Option {
+	return addHook(stageSetup, func(p *parser) error {
+		// Leave w alone, as the option may be shared by several parsers.
+		p.shadow = w
+		if p.shadow == nil {
+			p.shadow = p.warn
+		}
+		return nil
+	})
+}
+
+// EnvUsage returns an fx.Option that controls whether the usage output lists
+// the environment variables backing the flags when EnvPrefix is used.
+// It is enabled by default. The annotation wraps the flag set's usage
+// function, so it also applies to a function set with Usage, as long as it
+// prints the flags' help text, e.g. via PrintDefaults.
+func EnvUsage(show bool) Option {
+	return addHook(stageSetup, func(p *parser) error {
+		p.hideEnvUsage = !show
+		return nil
+	})
+}
+
+// wrapEnvUsage wraps the usage function of the flag set so that, while it
+// runs, the help text of each flag names the environment variables backing it.
+func (p *parser) wrapEnvUsage() {
+	if len(p.envPrefixes) == 0 || p.hideEnvUsage {
+		return
+	}
+	fs, usage := p.fs, p.fs.Usage
+	prefixes := p.envPrefixes
+	fs.Usage = func() {
+		restore := make(map[*flag.Flag]string)
+		fs.VisitAll(func(f *flag.Flag) {
+			keys := make([]string, len(prefixes))
+			for i, prefix := range prefixes {
+				keys[i] = envName(prefix, f.Name)
+			}
+			restore[f] = f.Usage
+			f.Usage += " (env: " + strings.Join(keys, ", ") + ")"
+		})
+		defer func() {
+			for f, u := range restore {
+				f.Usage = u
+			}
+		}()
+		if usage != nil {
+			usage()
+			return
+		}
+		defaultUsage(fs)
+	}
+}
+
+// checkEnvNames reports an error if two flags of the flag set map to the same
+// environment variable. Aliases, such as those of Deprecated, are skipped, as
+// they set the flag they stand for.
+func checkEnvNames(fs *flag.FlagSet, prefix string) error {
+	names := make(map[string]string)
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		if _, ok := f.Value.(*aliasValue); ok || err != nil {
+			return
+		}
+		key := envName(prefix, f.Name)
+		if other, ok := names[key]; ok {
+			err = fmt.Errorf("flagfx: flags -%s and -%s both map to environment variable %s", other, f.Name, key)
+			return
+		}
+		names[key] = f.Name
+	})
+	return err
+}
+
+// envNameReplacer maps the characters of a flag name that are not valid in
+// an environment variable name to underscores.
+var envNameReplacer = strings.NewReplacer("-", "_", ".", "_")
+
+// envName returns the environment variable backing the named flag.
+func envName(prefix, name string) string {
+	if prefix != "" {
+		name = prefix + "_" + name
+	}
+	return strings.ToUpper(envNameReplacer.Replace(name))
+}
+
+// ExportEnv writes the values as a shell script of export statements, one per
+// flag in sorted order, with the variable names EnvPrefix reads for the given
+// prefix, e.g.
+//
+//	export APP_LOG_LEVEL='info'
+//
+// so the resolved configuration can be handed to a child process. Values are
+// single-quoted, which keeps spaces and special characters intact.
+func (v Values) ExportEnv(prefix string, w io.Writer) error {
+	for _, name := range slices.Sorted(maps.Keys(v)) {
+		if _, err := fmt.Fprintf(w, "export %s=%s\n", envName(prefix, name), shellQuote(v[name])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package flagfx_test
+
+import (
+	"flag"
+	"io"
+	"strings"
+	"testing"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestValuesExportEnv(t *testing.T) {
+	values := flagfx.Values{
+		"log-level": "info",
+		"greeting":  "hello world",
+		"quote":     "it's $HOME; `rm -rf` \"x\"",
+		"db.dsn":    "",
+	}
+	var b strings.Builder
+	if err := values.ExportEnv("app", &b); err != nil {
+		t.Fatal(err)
+	}
+	want := `export APP_DB_DSN=''
+export APP_GREETING='hello world'
+export APP_LOG_LEVEL='info'
+export APP_QUOTE='it'\''s $HOME; ` + "`rm -rf`" + ` "x"'
+`
+	if got := b.String(); got != want {
+		t.Errorf("ExportEnv:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+type logFlags struct{ level *string }
+
+func newLogFlags(fs *flag.FlagSet) *logFlags {
+	return &logFlags{fs.String("log-level", "info", "")}
+}
+
+func TestEnvPrefixPrefixedModules(t *testing.T) {
+	env := map[string]string{
+		"APP_ACCESS_LOG_LEVEL": "debug",
+		"APP_ERROR_LOG_LEVEL":  "warn",
+		"APP_LOG_LEVEL":        "error", // Not read by the prefixed flags.
+	}
+	var access, errorLog *logFlags
+	var usage strings.Builder
+	fs := newTestFlagSet()
+	fs.SetOutput(&usage)
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(fs, nil),
+		flagfx.Env(func(key string) (string, bool) {
+			v, ok := env[key]
+			return v, ok
+		}),
+		flagfx.EnvPrefix("app"),
+		flagfx.Prefix("access")(fx.Annotated{Name: "access", Target: newLogFlags}),
+		flagfx.Prefix("error")(fx.Annotated{Name: "error", Target: newLogFlags}),
+		fx.Invoke(fx.Annotate(func(a, e *logFlags) { access, errorLog = a, e }, fx.ParamTags(`name:"access"`, `name:"error"`))),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if *access.level != "debug" || *errorLog.level != "warn" {
+		t.Errorf("-access.log-level=%s -error.log-level=%s, want debug and warn", *access.level, *errorLog.level)
+	}
+
+	fs.Usage()
+	for _, want := range []string{"(env: APP_ACCESS_LOG_LEVEL)", "(env: APP_ERROR_LOG_LEVEL)"} {
+		if !strings.Contains(usage.String(), want) {
+			t.Errorf("usage does not advertise %s:\n%s", want, usage.String())
+		}
+	}
+}
+
+func TestWarnOnShadow(t *testing.T) {
+	env := map[string]string{"APP_LOG_LEVEL": "warn", "APP_TOKEN": "s3cr3t"}
+	const shadowed = "flagfx: flag -log-level is set on the command line, which takes precedence over environment variable APP_LOG_LEVEL\n"
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, ""},
+		{[]string{"-port=1"}, ""},
+		{[]string{"-log-level=debug"}, shadowed},
+		{[]string{"-log-level=debug", "-token=x"}, shadowed +
+			"flagfx: flag -token is set on the command line, which takes precedence over environment variable APP_TOKEN\n"},
+	}
+	for _, nilWriter := range []bool{false, true} {
+		for _, tt := range tests {
+			var out, warnings strings.Builder
+			w := io.Writer(&out)
+			if nilWriter {
+				w = nil
+			}
+			fs := newTestFlagSet()
+			level := fs.String("log-level", "info", "")
+			fs.Int("port", 80, "")
+			flagfx.DefineSecret(fs, "token", "")
+			app := fx.New(
+				fx.NopLogger,
+				flagfx.ModuleFor(fs, tt.args),
+				flagfx.Env(func(key string) (string, bool) {
+					v, ok := env[key]
+					return v, ok
+				}),
+				flagfx.WarningOutput(&warnings),
+				flagfx.EnvPrefix("app"),
+				flagfx.WarnOnShadow(w),
+				fx.Invoke(func(flagfx.Ready) {}),
+			)
+			if err := app.Err(); err != nil {
+				t.Errorf("%q: %v", tt.args, err)
+				continue
+			}
+			got := out.String()
+			if nilWriter {
+				got = warnings.String()
+			}
+			if got != tt.want {
+				t.Errorf("%q: warnings %q, want %q", tt.args, got, tt.want)
+			}
+			if strings.Contains(got, "s3cr3t") {
+				t.Errorf("%q: warnings %q reveal a value", tt.args, got)
+			}
+			if tt.args == nil && *level != "warn" {
+				t.Errorf("-log-level = %s, want the environment applied", *level)
+			}
+		}
+	}
+}
+
+func TestWarnOnShadowShared(t *testing.T) {
+	shadow := flagfx.WarnOnShadow(nil)
+	for i := range 2 {
+		var warnings strings.Builder
+		fs := newTestFlagSet()
+		fs.String("log-level", "info", "")
+		app := fx.New(
+			fx.NopLogger,
+			flagfx.ModuleFor(fs, []string{"-log-level=debug"}),
+			flagfx.Env(func(key string) (string, bool) { return "warn", key == "APP_LOG_LEVEL" }),
+			flagfx.WarningOutput(&warnings),
+			flagfx.EnvPrefix("app"),
+			shadow,
+			fx.Invoke(func(flagfx.Ready) {}),
+		)
+		if err := app.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(warnings.String(), "-log-level") {
+			t.Errorf("app %d: warnings %q, want the shadow warning", i, warnings.String())
+		}
+	}
+}
+
+func TestEnvPrefixCollision(t *testing.T) {
+	tests := []struct {
+		names []string
+		err   string
+	}{
+		{[]string{"access.log-level", "error.log-level"}, ""},
+		{[]string{"access.log-level", "access-log-level"}, "flagfx: flags -access-log-level and -access.log-level both map to environment variable APP_ACCESS_LOG_LEVEL"},
+		{[]string{"port", "PORT"}, "flagfx: flags -PORT and -port both map to environment variable APP_PORT"},
+		{[]string{"log_level", "log-level"}, "flagfx: flags -log-level and -log_level both map to environment variable APP_LOG_LEVEL"},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		for _, name := range tt.names {
+			fs.String(name, "", "")
+		}
+		_, err := flagfx.ParseArgs(fs, nil, flagfx.EnvPrefix("app"))
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%q: %v", tt.names, err)
+			}
+		} else if err == nil || err.Error() != tt.err {
+			t.Errorf("%q: error %v, want %q", tt.names, err, tt.err)
+		}
+	}
+}
+
+func TestEnvPrefixCollisionAlias(t *testing.T) {
+	fs := newTestFlagSet()
+	fs.String("log-level", "info", "")
+	_, err := flagfx.ParseArgs(fs, nil, flagfx.EnvPrefix("app"), flagfx.Deprecated("log.level", "log-level"))
+	if err != nil {
+		t.Errorf("an alias collides with its flag: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/lftk/flagfx"
+	"go.uber.org/fx"
+)
+
+type flags struct {
+	Lines int
+}
+
+func main() {
+	app := fx.New(
+		// Disable fx's default logger for a clean output in this example.
+		fx.NopLogger,
+		// Add the core flagfx.Module to enable flag parsing.
+		flagfx.Module,
+		// Use flagfx.Provide to define flags.
+		flagfx.Provide(func(fs *flag.FlagSet) *flags {
+			var f flags
+			fs.IntVar(&f.Lines, "n", 10, "number of lines to print")
+			return &f
+		}),
+		// flagfx.Positional holds the arguments left over after parsing,
+		// here the name of the file to read.
+		fx.Invoke(func(f *flags, args flagfx.Positional) error {
+			if len(args) != 1 {
+				return errors.New("usage: head [-n lines] <file>")
+			}
+			return head(args[0], f.Lines)
+		}),
+	)
+	if err := app.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// head prints the first n lines of the named file.
+func head(name string, n int) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for i := 0; i < n && scanner.Scan(); i++ {
+		fmt.Println(scanner.Text())
+	}
+	return scanner.Err()
+}
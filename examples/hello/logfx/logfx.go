@@ -2,6 +2,8 @@ package logfx
 
 import (
 	"flag"
+	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/lftk/flagfx"
@@ -10,23 +12,63 @@ import (
 
 type LogLevel string
 
+// levels lists the log levels from the least to the most verbose.
+var levels = []LogLevel{"error", "warn", "info", "debug"}
+
 type flags struct {
-	Level string
+	Level     string
+	Verbosity *int
 }
 
 var Module = fx.Module("logfx",
 	// Use flagfx.Provide (instead of fx.Provide) to define flags.
-	flagfx.Provide(
-		func(fs *flag.FlagSet) *flags {
-			var f flags
-			fs.StringVar(&f.Level, "log-level", "info", "log level (e.g., debug, info, warn)")
-			return &f
-		},
-	),
-	// Provide a clean LogLevel type to the container, derived from the raw flag value.
-	fx.Provide(
-		func(f *flags) LogLevel {
-			return LogLevel(strings.ToLower(f.Level))
-		},
-	),
+	flagfx.Provide(newFlags),
+	// Provide a clean LogLevel type to the container, derived from the raw flag values.
+	fx.Provide(newLogLevel),
+	// Contribute the flags to the app's flagfx.Config. This uses fx.Provide, as
+	// the fragment depends on the flags, which are only available after parsing.
+	fx.Provide(fx.Annotate(newConfig("logfx"), fx.ResultTags(`group:"flagfx_config"`))),
+	// Add a note to the usage output. Notes are needed while parsing, when -h
+	// is given, so they are supplied directly rather than via flagfx.Provide.
+	fx.Supply(fx.Annotated{
+		Group:  "flagfx_usage",
+		Target: flagfx.UsageNote("Repeat -v for more verbose logging, e.g. -v -v."),
+	}),
 )
+
+// New returns an instance of the module whose flags are prefixed, so that
+// New("access") registers -access.log-level and -access.v. Several instances
+// can coexist; each one provides its LogLevel under the name of its prefix.
+func New(prefix string) fx.Option {
+	tag := fmt.Sprintf(`name:"%s"`, prefix)
+	return fx.Module("logfx."+prefix,
+		// Use flagfx.Prefix to register the flags under the prefix, and name the
+		// result so that it doesn't collide with other instances.
+		flagfx.Prefix(prefix)(fx.Annotated{Name: prefix, Target: newFlags}),
+		fx.Provide(fx.Annotate(newLogLevel, fx.ParamTags(tag), fx.ResultTags(tag))),
+		fx.Provide(fx.Annotate(newConfig("logfx."+prefix), fx.ParamTags(tag), fx.ResultTags(`group:"flagfx_config"`))),
+	)
+}
+
+func newFlags(fs *flag.FlagSet) *flags {
+	var f flags
+	fs.StringVar(&f.Level, "log-level", "info", "log level (e.g., debug, info, warn)")
+	// Each -v makes logging one level more verbose, e.g. -v -v turns warn into debug.
+	f.Verbosity = flagfx.DefineCount(fs, "v", "increase verbosity (repeatable)")
+	return &f
+}
+
+func newLogLevel(f *flags) LogLevel {
+	level := LogLevel(strings.ToLower(f.Level))
+	i := slices.Index(levels, level)
+	if i < 0 || *f.Verbosity == 0 {
+		return level
+	}
+	return levels[min(i+*f.Verbosity, len(levels)-1)]
+}
+
+func newConfig(name string) func(f *flags) flagfx.ConfigFragment {
+	return func(f *flags) flagfx.ConfigFragment {
+		return flagfx.ConfigFragment{Name: name, Value: f}
+	}
+}
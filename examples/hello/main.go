@@ -2,6 +2,9 @@ package main
 
 import (
 	"fmt"
+	"maps"
+	"os"
+	"slices"
 
 	"github.com/lftk/flagfx"
 	"github.com/lftk/flagfx/examples/hello/logfx"
@@ -10,7 +13,9 @@ import (
 )
 
 func main() {
-	app := fx.New(
+	// flagfx.Run builds and runs the app, and maps parse errors, help requests
+	// and other failures to the exit code.
+	os.Exit(flagfx.Run(
 		// Disable fx's default logger for a clean output in this example.
 		fx.NopLogger,
 		// Add the core flagfx.Module to enable flag parsing.
@@ -23,6 +28,11 @@ func main() {
 		fx.Invoke(func(level logfx.LogLevel) {
 			fmt.Println("Log level set to:", level)
 		}),
-	)
-	app.Run()
+		// Invoke a function that receives the configuration of all modules at once.
+		fx.Invoke(func(config flagfx.Config) {
+			for _, name := range slices.Sorted(maps.Keys(config)) {
+				fmt.Printf("Config %s: %+v\n", name, config[name])
+			}
+		}),
+	))
 }
@@ -3,7 +3,6 @@ package verfx
 import (
 	"flag"
 	"fmt"
-	"os"
 
 	"github.com/lftk/flagfx"
 	"go.uber.org/fx"
@@ -15,24 +14,39 @@ type flags struct {
 
 type version string
 
+// result contributes the flags to the app's flagfx.Config, next to
+// providing them to the module.
+type result struct {
+	fx.Out
+
+	Flags  *flags
+	Config flagfx.ConfigFragment `group:"flagfx_config"`
+}
+
 var Module = fx.Module("verfx",
 	// Use flagfx.Provide (instead of fx.Provide) to define flags.
 	flagfx.Provide(
-		func(fs *flag.FlagSet) *flags {
+		func(fs *flag.FlagSet) result {
 			var f flags
 			fs.BoolVar(&f.ShowVersion, "version", false, "show version")
-			return &f
+			return result{Flags: &f, Config: flagfx.ConfigFragment{Name: "verfx", Value: &f}}
 		},
 	),
 	// Invoke a function that checks the flag and acts accordingly.
+	// The injected flagfx.Exiter is used instead of os.Exit to keep it testable.
 	fx.Invoke(
-		func(f *flags, ver version) {
+		func(f *flags, ver version, exit flagfx.Exiter) {
 			if f.ShowVersion {
 				fmt.Println("Version:", ver)
-				os.Exit(0)
+				exit(0)
 			}
 		},
 	),
+	// Add a note to the usage output, next to the notes of other modules.
+	fx.Supply(fx.Annotated{
+		Group:  "flagfx_usage",
+		Target: flagfx.UsageNote("Use -version to print the version and exit."),
+	}),
 	// Supply a default version string, which can be overridden.
 	fx.Supply(version("unknown")),
 )
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lftk/flagfx/pflagfx"
+	"github.com/spf13/pflag"
+	"go.uber.org/fx"
+)
+
+type flags struct {
+	Level       string
+	ShowVersion bool
+}
+
+func main() {
+	app := fx.New(
+		// Disable fx's default logger for a clean output in this example.
+		fx.NopLogger,
+		// Add the pflagfx.Module instead of flagfx.Module to parse GNU-style flags.
+		pflagfx.Module,
+		// Use pflagfx.Provide to define flags with long and short names.
+		pflagfx.Provide(func(fs *pflag.FlagSet) *flags {
+			var f flags
+			fs.StringVarP(&f.Level, "log-level", "l", "info", "log level (e.g., debug, info, warn)")
+			fs.BoolVarP(&f.ShowVersion, "version", "V", false, "show version")
+			return &f
+		}),
+		// Use the parsed flag values, e.g. `-V` or `--log-level=debug`.
+		fx.Invoke(func(f *flags) {
+			if f.ShowVersion {
+				fmt.Println("Version: v0.1.0")
+				return
+			}
+			fmt.Println("Log level set to:", strings.ToLower(f.Level))
+		}),
+	)
+	if err := app.Err(); err != nil {
+		fmt.Println(err)
+	}
+}
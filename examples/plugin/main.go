@@ -0,0 +1,51 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/lftk/flagfx"
+	"go.uber.org/fx"
+)
+
+type flags struct {
+	Name string
+}
+
+type pluginFlags struct {
+	Verbose bool
+}
+
+func main() {
+	app := fx.New(
+		// Disable fx's default logger for a clean output in this example.
+		fx.NopLogger,
+		// Add the core flagfx.Module to enable flag parsing.
+		flagfx.Module,
+		// The app defines its flags on the active flag set as usual.
+		flagfx.Provide(func(fs *flag.FlagSet) *flags {
+			var f flags
+			fs.StringVar(&f.Name, "name", "World", "name to greet")
+			return &f
+		}),
+		// The plugin asks for a child flag set at startup. Run with
+		// -name=Gopher -plugin.verbose: each set only sees its own flags.
+		flagfx.Provide(func(child flagfx.Child) *pluginFlags {
+			fs := child("plugin")
+			var f pluginFlags
+			fs.BoolVar(&f.Verbose, "plugin.verbose", false, "verbose plugin output")
+			return &f
+		}),
+		fx.Invoke(func(f *flags, pf *pluginFlags) {
+			if pf.Verbose {
+				fmt.Println("plugin: greeting", f.Name)
+			}
+			fmt.Printf("Hello, %s!\n", f.Name)
+		}),
+	)
+	if err := app.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/lftk/flagfx"
+	"github.com/lftk/flagfx/examples/hello/logfx"
+	"go.uber.org/fx"
+)
+
+func main() {
+	app := fx.New(
+		// Disable fx's default logger for a clean output in this example.
+		fx.NopLogger,
+		// Add the core flagfx.Module to enable flag parsing.
+		flagfx.Module,
+		// Instantiate logfx twice. Their flags are registered with a prefix,
+		// e.g. -access.log-level and -error.log-level, so they don't collide.
+		logfx.New("access"), logfx.New("error"),
+		// Each instance provides its LogLevel under the name of its prefix.
+		fx.Invoke(fx.Annotate(
+			func(access, errors logfx.LogLevel) {
+				fmt.Println("Access log level:", access)
+				fmt.Println("Error log level:", errors)
+			},
+			fx.ParamTags(`name:"access"`, `name:"error"`),
+		)),
+	)
+	if err := app.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/lftk/flagfx"
+	"go.uber.org/fx"
+)
+
+type flags struct {
+	Addr     string
+	LogLevel string
+	Debug    bool
+}
+
+func main() {
+	app := fx.New(
+		// Disable fx's default logger for a clean output in this example.
+		fx.NopLogger,
+		// Add the core flagfx.Module to enable flag parsing.
+		flagfx.Module,
+		// Use flagfx.Provide to define flags.
+		flagfx.Provide(func(fs *flag.FlagSet) *flags {
+			var f flags
+			fs.StringVar(&f.Addr, "addr", ":8080", "listen address")
+			fs.StringVar(&f.LogLevel, "log-level", "info", "log level")
+			fs.BoolVar(&f.Debug, "debug", false, "enable debug endpoints")
+			return &f
+		}),
+		// Define the presets selected with -preset=dev or -preset=prod. Flags
+		// given on the command line win over the preset, e.g.
+		// -preset=prod -log-level=debug.
+		flagfx.Preset("dev", map[string]string{
+			"addr":      "localhost:8080",
+			"log-level": "debug",
+			"debug":     "true",
+		}),
+		flagfx.Preset("prod", map[string]string{
+			"addr":      ":80",
+			"log-level": "warn",
+		}),
+		fx.Invoke(func(f *flags) {
+			fmt.Printf("addr=%s log-level=%s debug=%t\n", f.Addr, f.LogLevel, f.Debug)
+		}),
+	)
+	if err := app.Err(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,57 @@
+package flagfx
+
+import (
+	"fmt"
+	"os"
+
+	"go.uber.org/fx"
+)
+
+// Exiter terminates the program with the given status code.
+// Modules that need to short-circuit, e.g. after printing the version, should
+// inject an Exiter and call it instead of calling os.Exit directly.
+type Exiter func(code int)
+
+// defaultExiter provides the default Exiter, which is os.Exit.
+// This can be replaced using the Exit option.
+func defaultExiter() Exiter {
+	return os.Exit
+}
+
+// Exit allows replacing the default Exiter (os.Exit). In tests, the
+// replacement can record the code and panic to unwind instead of exiting.
+func Exit(fn func(code int)) fx.Option {
+	return fx.Replace(Exiter(fn))
+}
+
+// PrintAndExit returns an fx.Option that registers a boolean flag which, when
+// set, prints the result of fn to the output of the flag set, see
+// flag.FlagSet.SetOutput, and exits cleanly with code 0 through the Exiter,
+// e.g. for -version:
+//
+//	flagfx.PrintAndExit("version", "print the version", func() string {
+//		return version
+//	})
+//
+// The flag is informational: it is handled right after parsing, before
+// fallbacks such as EnvPrefix and checks such as Required and Validate, which
+// are skipped, so it works even if the rest of the command line is
+// incomplete. Several such flags can coexist; if more than one is set, only
+// the first one registered prints.
+func PrintAndExit(flagName, usage string, fn func() string) Option {
+	return options(
+		addHook(stageSetup, func(p *parser) error {
+			optionVar(p.fs, flagName, usage, optionBool(false))
+			return nil
+		}),
+		addHook(stageInfo, func(p *parser) error {
+			// Read the flag from the parsed flag set rather than keeping it
+			// in the option, which may be shared by several parsers.
+			if p.fs.Lookup(flagName).Value.String() == "true" {
+				fmt.Fprintln(p.fs.Output(), fn())
+				p.quit()
+			}
+			return nil
+		}),
+	)
+}
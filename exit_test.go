@@ -0,0 +1,60 @@
+package flagfx_test
+
+import (
+	"bytes"
+	"testing"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestPrintAndExit(t *testing.T) {
+	tests := []struct {
+		args []string
+		out  string
+		code int // -1 if the app must not exit.
+	}{
+		{nil, "", -1},
+		{[]string{"-version"}, "v1.2.3\n", 0},
+		{[]string{"-version", "-commit"}, "v1.2.3\n", 0},
+		{[]string{"-commit"}, "abc123\n", 0},
+		// Informational flags skip the checks, see Required below.
+		{[]string{"-version=false"}, "", -1},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		code := -1
+		fs := newTestFlagSet()
+		fs.SetOutput(&out)
+		fs.Int("port", 80, "")
+		var err error
+		stdout := captureStdout(t, func() {
+			err = fx.New(
+				fx.NopLogger,
+				flagfx.ModuleFor(fs, tt.args),
+				flagfx.Exit(func(c int) { code = c }),
+				flagfx.PrintAndExit("version", "print the version", func() string { return "v1.2.3" }),
+				flagfx.PrintAndExit("commit", "print the commit", func() string { return "abc123" }),
+				flagfx.Required("port"),
+				fx.Invoke(func(flagfx.Ready) {}),
+			).Err()
+		})
+		if tt.code < 0 {
+			if err == nil {
+				t.Errorf("%q: got no error, want the missing -port reported", tt.args)
+			}
+		} else if err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+		}
+		if got := out.String(); got != tt.out {
+			t.Errorf("%q: output %q, want %q", tt.args, got, tt.out)
+		}
+		if len(stdout) > 0 {
+			t.Errorf("%q: wrote %q to stdout, want the output of the flag set", tt.args, stdout)
+		}
+		if code != tt.code {
+			t.Errorf("%q: exit code %d, want %d", tt.args, code, tt.code)
+		}
+	}
+}
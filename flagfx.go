@@ -5,6 +5,7 @@ package flagfx
 
 import (
 	"flag"
+	"fmt"
 	"os"
 
 	"go.uber.org/fx"
@@ -13,18 +14,64 @@ import (
 )
 
 // Module is the core `fx.Module` for the flagfx system.
-var Module = fx.Module("flagfx",
-	// Provide the default dependencies for the parse action.
+// It parses the global flag.CommandLine with os.Args[1:], see IsolatedModule
+// and ModuleFor to avoid the global flag set.
+var Module = newModule(
 	fx.Provide(defaultFlagSet, defaultArgs),
-	// The barrier ensures that flags are parsed before any constructors provided
-	// via this module's Provide function are invoked.
-	fxbarrier.Barrier("flagfx",
-		func(fs *flag.FlagSet, args Arguments) error {
-			return fs.Parse(args)
-		},
-	),
 )
 
+// IsolatedModule is a variant of Module that parses os.Args[1:] against a
+// fresh flag set created for each app, instead of flag.CommandLine. Flags
+// registered by one app therefore never leak into another, so several apps in
+// one process, e.g. in tests or a plugin host, can be constructed one after
+// the other without "flag redefined" panics. The flag set is named after
+// os.Args[0] and exits on errors, like flag.CommandLine; flags registered on
+// flag.CommandLine by other packages are not part of it.
+var IsolatedModule = newModule(
+	fx.Provide(newIsolatedFlagSet, defaultArgs),
+)
+
+// newIsolatedFlagSet provides a fresh flag set for IsolatedModule.
+func newIsolatedFlagSet() *flag.FlagSet {
+	return flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+}
+
+// ModuleFor returns a variant of Module bound to the given flag set and
+// arguments, for libraries embedding flagfx in a larger app. Unlike Module, it
+// never uses flag.CommandLine or os.Args, so several apps in one process,
+// e.g. in tests, don't interfere with each other or with the process-global
+// flag set. It is a shorthand for Module combined with FlagSet and Args.
+//
+// Other process defaults, such as looking up environment variables with
+// os.LookupEnv and exiting with os.Exit, still apply and can be replaced with
+// options like Env and Exit.
+func ModuleFor(fs *flag.FlagSet, args []string) fx.Option {
+	return newModule(
+		fx.Supply(fs, Arguments(args)),
+	)
+}
+
+// newModule builds the flagfx module, with source providing the flag set and
+// the arguments to parse.
+func newModule(source fx.Option) fx.Option {
+	return fx.Module("flagfx",
+		source,
+		// Provide the default dependencies for the parse action.
+		fx.Provide(
+			defaultEnvLookup, defaultExiter, defaultWarningOutput,
+			defaultParseContext, defaultNow, newParser, newProgramName, newChild,
+			newOwners, newFlagSetHandle, defaultVersion,
+		),
+		// The barrier ensures that flags are parsed before any constructors provided
+		// via this module's Provide function are invoked.
+		fxbarrier.Barrier("flagfx", parseAll),
+		// The result is only handed out once the barrier is lifted, so anything
+		// derived from it observes the parsed flag set.
+		fxbarrier.Provide("flagfx", newResult),
+		fx.Provide(provideResult, newConfig),
+	)
+}
+
 // defaultFlagSet provides the default flag set, which is the global flag.CommandLine.
 // This can be replaced using the FlagSet option.
 func defaultFlagSet() *flag.FlagSet {
@@ -37,23 +84,184 @@ func defaultArgs() Arguments {
 	return os.Args[1:]
 }
 
+// ParseError is returned when the flag set fails to parse the Arguments.
+// It wraps the error reported by the flag package, so errors.Is(err, flag.ErrHelp)
+// keeps working and errors.As can be used to tell parse failures apart from
+// constructor failures.
+type ParseError struct {
+	Args Arguments // The arguments that failed to parse.
+	Err  error     // The error returned by flag.FlagSet.Parse.
+}
+
+// Error implements the error interface.
+func (e ParseError) Error() string {
+	return fmt.Sprintf("flagfx: parse arguments: %v", e.Err)
+}
+
+// Unwrap returns the underlying parse error.
+func (e ParseError) Unwrap() error {
+	return e.Err
+}
+
 // FlagSet allows replacing the default `*flag.FlagSet` (which is flag.CommandLine)
 // with a custom one.
 func FlagSet(fs *flag.FlagSet) fx.Option {
 	return fx.Replace(fs)
 }
 
+// AddFlags returns an fx.Option that registers extra flags with the active flag
+// set by calling fn before parsing. Unlike FlagSet it does not replace the
+// flag set, so app-level code can add a few flags without writing a module.
+// fn runs after the constructors passed to Provide have registered their
+// flags, and multiple AddFlags run in registration order. Registering a flag
+// that is already defined fails the app with an error naming the flag.
+func AddFlags(fn func(fs *flag.FlagSet)) Option {
+	add := catchRedefined(func(fs *flag.FlagSet) { fn(fs) }).(func(*flag.FlagSet) error)
+	return addHook(stageSetup, func(p *parser) error {
+		return add(p.fs)
+	})
+}
+
+// ErrorHandling re-initializes the active flag set (the default one or the one
+// supplied via FlagSet) with the given error handling mode before parsing.
+// With flag.ContinueOnError, a parse failure is returned as a ParseError from
+// the fx app instead of exiting the process.
+// When this option is absent, the flag set's own error handling mode is respected.
+func ErrorHandling(h flag.ErrorHandling) Option {
+	return addHook(stageSetup, func(p *parser) error {
+		p.fs.Init(p.fs.Name(), h)
+		return nil
+	})
+}
+
+// ForceParse returns an fx.Option that makes the barrier parse the flag set
+// even if it has already been parsed, e.g. by a call to flag.Parse elsewhere
+// in a larger app.
+//
+// By default the barrier leaves such a flag set untouched: the values it was
+// parsed with stay in effect, and hooks such as EnvPrefix, ConfigFile,
+// Required and AfterParse do not run. Code that calls flag.Parse after the
+// barrier parses flag.CommandLine again, with os.Args, overwriting the values
+// seen by flagfx; avoid this by depending on Parsed instead of parsing again.
+//
+// Options registering flags of their own, such as Preset, DryRun or
+// PrintAndExit, reuse the flags registered by a previous parse of the flag set
+// and reset them to their defaults.
+func ForceParse() Option {
+	return addHook(stageSetup, func(p *parser) error {
+		p.force = true
+		return nil
+	})
+}
+
+// AfterParse returns an fx.Option that runs fn inside the barrier once the
+// flag set has been parsed and fallbacks such as EnvPrefix have been applied,
+// before any dependent constructor runs. A returned error aborts the app.
+// Multiple AfterParse hooks run in registration order, and the first error
+// stops the remaining ones.
+func AfterParse(fn func(fs *flag.FlagSet) error) Option {
+	return addHook(stageParsed, func(p *parser) error {
+		return fn(p.fs)
+	})
+}
+
 // Arguments represents the command-line Arguments to be parsed.
+// The injected value always holds the original arguments, even after options
+// such as ResponseFiles have rewritten the ones being parsed.
 type Arguments []string
 
+// ProgramName is the name of the program, e.g. for printing
+// "usage: <prog> ..." without hardcoding it. It is the name of the active
+// flag set (os.Args[0] for flag.CommandLine), or os.Args[0] if that is empty.
+type ProgramName string
+
+// newProgramName provides the ProgramName of the active flag set.
+func newProgramName(fs *flag.FlagSet) ProgramName {
+	if name := fs.Name(); name != "" {
+		return ProgramName(name)
+	}
+	return ProgramName(os.Args[0])
+}
+
 // Args allows replacing the default command-line arguments (os.Args[1:])
 // with a custom slice of strings.
 func Args(args []string) fx.Option {
 	return fx.Replace(Arguments(args))
 }
 
+// ArgsFunc is like Args, but the arguments are computed by fn when they are
+// needed for parsing, during fx startup, rather than when the option is
+// constructed, e.g. to read them from a file descriptor. An error returned by
+// fn fails the app. Options that rewrite the arguments, such as
+// ResponseFiles, apply to the arguments returned by fn.
+func ArgsFunc(fn func() ([]string, error)) fx.Option {
+	return fx.Decorate(func() (Arguments, error) {
+		args, err := fn()
+		if err != nil {
+			return nil, fmt.Errorf("flagfx: supply arguments: %w", err)
+		}
+		return Arguments(args), nil
+	})
+}
+
 // Provide is a wrapper around fxbarrier.Provide for use with command-line flags.
 // It uses the "flagfx" barrier to ensure flags are parsed before dependents are instantiated.
+// A constructor registering a flag that is already defined fails with an error
+// naming the flag, instead of panicking.
+//
+// A constructor may be wrapped in an fx.Annotated to provide its results with
+// a name or into a value group, e.g. to use a module several times in one app.
 func Provide(constructors ...any) fx.Option {
-	return fxbarrier.Provide("flagfx", constructors...)
+	wrapped := make([]any, len(constructors))
+	for i, c := range constructors {
+		if a, ok := c.(fx.Annotated); ok {
+			c = annotateResults(a)
+		}
+		wrapped[i] = catchRedefined(c)
+	}
+	return fxbarrier.Provide("flagfx", wrapped...)
+}
+
+// ProvideUnbarriered is like Provide, but bypasses the flagfx barrier: the
+// constructors are provided with fx.Provide, so their results are handed out
+// without waiting for parsing. It is an escape hatch for optional modules that
+// are fine with the defaults of their flags, e.g. a diagnostic module added
+// conditionally.
+//
+// Use it with care. The constructors still register their flags on the active
+// flag set, so the flags are listed by the usage output, but whether they are
+// parsed depends on when fx happens to call the constructors: if that is after
+// the barrier, the flags silently keep their defaults whatever the command
+// line says, and StrictBarrier reports them as registered after parsing. Use
+// Provide unless ignoring the command line is acceptable.
+func ProvideUnbarriered(constructors ...any) fx.Option {
+	wrapped := make([]any, len(constructors))
+	for i, c := range constructors {
+		if a, ok := c.(fx.Annotated); ok {
+			a.Target = catchRedefined(a.Target)
+			c = a
+		} else {
+			c = catchRedefined(c)
+		}
+		wrapped[i] = c
+	}
+	return fx.Provide(wrapped...)
+}
+
+// Prefix returns a ProvideFunc whose constructors register their flags
+// under the given prefix, so that -port becomes -prefix.port. This lets the
+// flags of several modules, or of several instances of one module, coexist.
+//
+// The *flag.FlagSet or FlagSetHandle passed to such constructors is only
+// meant for registering flags; it is never parsed itself. With EnvPrefix, the
+// environment variables of the flags carry the prefix as well, e.g.
+// APP_ACCESS_LOG_LEVEL for -access.log-level.
+func Prefix(prefix string) ProvideFunc {
+	return func(constructors ...any) fx.Option {
+		prefixed := make([]any, len(constructors))
+		for i, c := range constructors {
+			prefixed[i] = prefixFlags(c, prefix)
+		}
+		return Provide(prefixed...)
+	}
 }
@@ -0,0 +1,108 @@
+package flagfx_test
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+type portFlag struct{ port *int }
+
+type hostFlag struct{ host *string }
+
+func TestProvideDuplicateFlag(t *testing.T) {
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), nil),
+		flagfx.Provide(
+			func(fs *flag.FlagSet) *portFlag { return &portFlag{fs.Int("port", 80, "")} },
+			func(fs *flag.FlagSet) *hostFlag { return &hostFlag{fs.String("port", "", "")} },
+		),
+		fx.Invoke(func(*portFlag, *hostFlag) {}),
+	)
+	err := app.Err()
+	if err == nil || !strings.Contains(err.Error(), `flag "port" already registered by another module`) {
+		t.Errorf("error %v, want the duplicate -port named", err)
+	}
+}
+
+func TestProvideDistinctFlags(t *testing.T) {
+	var p *portFlag
+	var h *hostFlag
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), []string{"-port=8080", "-host=example.com"}),
+		flagfx.Provide(
+			func(fs *flag.FlagSet) *portFlag { return &portFlag{fs.Int("port", 80, "")} },
+			func(fs *flag.FlagSet) (*hostFlag, error) { return &hostFlag{fs.String("host", "", "")}, nil },
+		),
+		fx.Populate(&p, &h),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if *p.port != 8080 || *h.host != "example.com" {
+		t.Errorf("-port=%d -host=%s", *p.port, *h.host)
+	}
+}
+
+func TestModuleForIndependent(t *testing.T) {
+	newApp := func(args ...string) (*fx.App, *portFlag) {
+		var p *portFlag
+		app := fx.New(
+			fx.NopLogger,
+			flagfx.ModuleFor(newTestFlagSet(), args),
+			flagfx.Provide(func(fs *flag.FlagSet) *portFlag { return &portFlag{fs.Int("port", 80, "")} }),
+			fx.Populate(&p),
+		)
+		return app, p
+	}
+	// Both apps register -port, each with its own flag set and arguments.
+	app1, p1 := newApp("-port=1")
+	app2, p2 := newApp("-port=2")
+	if err := errors.Join(app1.Err(), app2.Err()); err != nil {
+		t.Fatal(err)
+	}
+	if *p1.port != 1 || *p2.port != 2 {
+		t.Errorf("-port = %d and %d, want 1 and 2", *p1.port, *p2.port)
+	}
+	if flag.CommandLine.Lookup("port") != nil {
+		t.Error("-port is registered with flag.CommandLine")
+	}
+}
+
+func TestIsolatedModule(t *testing.T) {
+	// The apps are constructed and started one after the other, each
+	// registering -port with a flag set of its own.
+	for i, args := range [][]string{{"-port=1"}, {"-port=2"}} {
+		var p *portFlag
+		var fs *flag.FlagSet
+		app := fx.New(
+			fx.NopLogger,
+			flagfx.IsolatedModule,
+			flagfx.Args(args),
+			flagfx.Provide(func(fs *flag.FlagSet) *portFlag { return &portFlag{fs.Int("port", 80, "")} }),
+			fx.Populate(&p, &fs),
+		)
+		if err := app.Start(t.Context()); err != nil {
+			t.Fatalf("app %d: %v", i+1, err)
+		}
+		if err := app.Stop(t.Context()); err != nil {
+			t.Fatalf("app %d: %v", i+1, err)
+		}
+		if want := i + 1; *p.port != want {
+			t.Errorf("app %d: -port = %d, want %d", i+1, *p.port, want)
+		}
+		if fs == flag.CommandLine {
+			t.Errorf("app %d: flag set is flag.CommandLine", i+1)
+		}
+	}
+	if flag.CommandLine.Lookup("port") != nil {
+		t.Error("-port is registered with flag.CommandLine")
+	}
+}
@@ -0,0 +1,96 @@
+// Package flagfxtest provides helpers for testing fx apps that use flagfx,
+// without touching the global flag.CommandLine or exiting the process.
+package flagfxtest
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"strings"
+	"testing"
+
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxtest"
+
+	"github.com/lftk/flagfx"
+)
+
+// WithArgs returns an fx.Option that parses the given arguments against a
+// fresh flag set instead of flag.CommandLine and os.Args. Parse failures are
+// reported as errors from the app (flag.ContinueOnError), and the output of
+// the flag set, such as usage, is written to the test log.
+//
+// Asking for help with -h calls the flagfx.Exiter, which exits the process by
+// default; use flagfx.Exit to intercept it.
+func WithArgs(t testing.TB, args ...string) fx.Option {
+	fs := flag.NewFlagSet(t.Name(), flag.ContinueOnError)
+	fs.SetOutput(logWriter{t})
+	// Logging after the test has completed panics, so stop forwarding output.
+	t.Cleanup(func() { fs.SetOutput(io.Discard) })
+
+	return fx.Options(
+		flagfx.FlagSet(fs),
+		flagfx.ErrorHandling(flag.ContinueOnError),
+		flagfx.Args(args),
+	)
+}
+
+// AssertValue builds and starts an app from the given options, and fails the
+// test unless the named flag resolves to want, as reported by flagfx.Lookup.
+// The resolved value includes fallbacks such as EnvPrefix. Errors building or
+// starting the app fail the test; for a ParseError, the test fails with the
+// parse error alone. The app is stopped before AssertValue returns.
+//
+// app holds the options of the whole app rather than an *fx.App, since the
+// value can only be read from inside the app. Combined with WithArgs, this
+// keeps table-driven tests short:
+//
+//	for _, tt := range tests {
+//		flagfxtest.AssertValue(t, fx.Options(
+//			flagfx.Module,
+//			flagfx.Provide(newConfig),
+//			flagfxtest.WithArgs(t, tt.args...),
+//		), "addr", tt.want)
+//	}
+func AssertValue(t testing.TB, app fx.Option, name, want string) {
+	t.Helper()
+
+	var lookup flagfx.Lookup
+	a := fx.New(fxtest.WithTestLogger(t), app, fx.Populate(&lookup))
+	if err := a.Err(); err != nil {
+		// Report the parse error itself rather than the dependency chain
+		// that led to the barrier.
+		var perr flagfx.ParseError
+		if errors.As(err, &perr) {
+			err = perr
+		}
+		t.Fatalf("flagfxtest: build app: %v", err)
+	}
+	if err := a.Start(context.Background()); err != nil {
+		t.Fatalf("flagfxtest: start app: %v", err)
+	}
+	defer func() {
+		if err := a.Stop(context.Background()); err != nil {
+			t.Errorf("flagfxtest: stop app: %v", err)
+		}
+	}()
+
+	v, ok := lookup.Get(name)
+	if !ok {
+		t.Fatalf("flagfxtest: flag -%s is not defined", name)
+	}
+	if got := v.String(); got != want {
+		t.Errorf("flagfxtest: flag -%s = %q, want %q", name, got, want)
+	}
+}
+
+// logWriter is an io.Writer that writes to the test log.
+type logWriter struct {
+	t testing.TB
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	w.t.Log(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
@@ -0,0 +1,89 @@
+package flagfxtest_test
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+	"github.com/lftk/flagfx/flagfxtest"
+)
+
+// recorder is a testing.TB that records failures instead of failing the
+// test. Fatalf ends the calling goroutine, like testing.T does.
+type recorder struct {
+	testing.TB
+	failures []string
+	fatal    bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+	r.fatal = true
+	runtime.Goexit()
+}
+
+func newAddr(fs *flag.FlagSet) *string {
+	return fs.String("addr", ":8080", "address to listen on")
+}
+
+func TestAssertValue(t *testing.T) {
+	tests := []struct {
+		args  []string
+		name  string
+		want  string
+		fail  string // The failure reported, if any.
+		fatal bool
+	}{
+		{nil, "addr", ":8080", "", false},
+		{[]string{"-addr=:9090"}, "addr", ":9090", "", false},
+		{[]string{"-addr=:9090"}, "addr", ":8080", `flagfxtest: flag -addr = ":9090", want ":8080"`, false},
+		{nil, "port", "80", "flagfxtest: flag -port is not defined", true},
+		{[]string{"-port=80"}, "addr", ":8080", "flagfxtest: build app: flagfx: parse arguments: flag provided but not defined: -port", true},
+	}
+	for _, tt := range tests {
+		r := &recorder{TB: t}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			flagfxtest.AssertValue(r, fx.Options(
+				flagfx.Module,
+				flagfx.Provide(newAddr),
+				flagfxtest.WithArgs(t, tt.args...),
+			), tt.name, tt.want)
+		}()
+		<-done
+		switch {
+		case tt.fail == "" && len(r.failures) > 0:
+			t.Errorf("%q -%s: unexpected failures %q", tt.args, tt.name, r.failures)
+		case tt.fail != "" && (len(r.failures) != 1 || r.failures[0] != tt.fail):
+			t.Errorf("%q -%s: failures %q, want %q", tt.args, tt.name, r.failures, tt.fail)
+		case r.fatal != tt.fatal:
+			t.Errorf("%q -%s: fatal = %t, want %t", tt.args, tt.name, r.fatal, tt.fatal)
+		}
+	}
+}
+
+func TestAssertValueEnv(t *testing.T) {
+	r := &recorder{TB: t}
+	flagfxtest.AssertValue(r, fx.Options(
+		flagfx.Module,
+		flagfx.Provide(newAddr),
+		flagfxtest.WithArgs(t),
+		flagfx.Env(func(key string) (string, bool) { return ":7070", key == "APP_ADDR" }),
+		flagfx.EnvPrefix("app"),
+	), "addr", ":7070")
+	if len(r.failures) > 0 {
+		t.Errorf("unexpected failures %q", strings.Join(r.failures, "; "))
+	}
+}
@@ -0,0 +1,17 @@
+// Package flagfxyaml loads flagfx flag values from YAML documents. It is a
+// separate package so that only apps importing it compile and link a YAML
+// decoder; as yaml.v3 is a requirement of the flagfx module, it is still
+// listed in the go.mod of apps using flagfx.
+package flagfxyaml
+
+import (
+	"gopkg.in/yaml.v3"
+
+	"github.com/lftk/flagfx"
+)
+
+// ConfigYAML is like flagfx.ConfigJSON, but loads the flag values from a YAML
+// document, where keyFor returns dotted paths through nested mappings.
+func ConfigYAML(path string, keyFor func(flagName string) string) flagfx.Option {
+	return flagfx.ConfigDocument(path, yaml.Unmarshal, keyFor)
+}
@@ -0,0 +1,62 @@
+package flagfx
+
+import (
+	"fmt"
+
+	"go.uber.org/fx"
+)
+
+// ConfigFragment is the part of the configuration contributed by a module,
+// typically the struct its flags are parsed into. Fragments are collected
+// from the value group "flagfx_config" into Config.
+//
+// A constructor passed to Provide can contribute a fragment by returning it
+// in an fx.Out struct, next to its other results:
+//
+//	type result struct {
+//		fx.Out
+//
+//		Flags  *flags
+//		Config flagfx.ConfigFragment `group:"flagfx_config"`
+//	}
+//
+//	flagfx.Provide(func(fs *flag.FlagSet) result {
+//		f := &flags{}
+//		fs.StringVar(&f.Addr, "addr", ":8080", "listen address")
+//		return result{Flags: f, Config: flagfx.ConfigFragment{Name: "server", Value: f}}
+//	})
+//
+// Such a constructor runs before parsing, so Value should be a pointer that
+// observes the parsed values. A fragment derived from the parsed values can be
+// contributed with fx.Provide and fx.ResultTags(`group:"flagfx_config"`) as well.
+type ConfigFragment struct {
+	Name  string // Identifies the fragment, e.g. the name of the module.
+	Value any    // The configuration, e.g. a pointer to the module's flags.
+}
+
+// Config maps the name of every ConfigFragment to its value, so a single
+// consumer can log or validate the configuration of all modules.
+//
+// Like Positional, Config must be consumed via fx.Provide or fx.Invoke.
+type Config map[string]any
+
+// configParams holds the fragments assembled into Config.
+type configParams struct {
+	fx.In
+
+	Result    result
+	Fragments []ConfigFragment `group:"flagfx_config"`
+}
+
+// newConfig assembles the fragments into Config once the flags are parsed.
+// Two fragments with the same name are an error.
+func newConfig(params configParams) (Config, error) {
+	config := make(Config, len(params.Fragments))
+	for _, f := range params.Fragments {
+		if _, ok := config[f.Name]; ok {
+			return nil, fmt.Errorf("flagfx: duplicate config fragment %q", f.Name)
+		}
+		config[f.Name] = f.Value
+	}
+	return config, nil
+}
@@ -4,7 +4,9 @@ go 1.24
 
 require (
 	github.com/lftk/fxbarrier v0.1.0
+	github.com/spf13/pflag v1.0.10
 	go.uber.org/fx v1.24.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,30 @@
+package flagfx_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// checkGolden compares got with the golden file testdata/name, or writes got
+// to it with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v; run go test -update to create it", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("output differs from %s; run go test -update if the change is intended:\n--- got\n%s\n--- want\n%s", path, got, want)
+	}
+}
@@ -0,0 +1,116 @@
+package flagfx
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+)
+
+// Group returns an fx.Option that lists the named flags under a header in the
+// usage output, for tools with many flags; Group("Logging", "log-level") lists
+// -log-level under "Logging:". Groups are listed in the order they were first
+// registered, the flags of each group in the sorted order of PrintDefaults,
+// and flags that belong to no group come first under "Flags:". Using Group
+// several times with the same header adds to that group. Naming a flag that
+// is not defined in the flag set, or a flag that is already in another group,
+// is a configuration error.
+//
+//	Usage of app:
+//
+//	Flags:
+//	  -v	verbose output
+//
+//	Logging:
+//	  -log-level string
+//	    	log level (default "info")
+//
+// Grouping is presentation only. Like Hidden, it rearranges whatever the usage
+// function of the flag set writes, as long as it lists the flags in the format
+// of PrintDefaults.
+func Group(header string, names ...string) Option {
+	return addHook(stageSetup, func(p *parser) error {
+		p.groups = append(p.groups, usageGroup{header: header, names: names})
+		return nil
+	})
+}
+
+// usageGroup is a group of flags registered with Group.
+type usageGroup struct {
+	header string
+	names  []string
+}
+
+// ungroupedHeader is the header of the flags that belong to no group.
+const ungroupedHeader = "Flags"
+
+// wrapGroupUsage wraps the usage function of the flag set so that the entries
+// of its output are listed under the headers of their groups.
+func (p *parser) wrapGroupUsage() error {
+	if len(p.groups) == 0 {
+		return nil
+	}
+	groups := make(map[string]string)
+	headers := []string{ungroupedHeader}
+	for _, g := range p.groups {
+		if err := lookupAll(p.fs, "grouped", g.names); err != nil {
+			return err
+		}
+		if !slices.Contains(headers, g.header) {
+			headers = append(headers, g.header)
+		}
+		for _, name := range g.names {
+			if header, ok := groups[name]; ok && header != g.header {
+				return fmt.Errorf("flagfx: flag -%s is in groups %q and %q", name, header, g.header)
+			}
+			groups[name] = g.header
+		}
+	}
+	rewriteUsage(p.fs, func(usage []byte) []byte {
+		return groupUsage(usage, groups, headers)
+	})
+	return nil
+}
+
+// groupUsage rearranges the first run of flag entries in usage output in the
+// format of PrintDefaults by group, listing each non-empty group under its
+// header and separating the groups by blank lines. The lines before and after
+// the entries are kept as is.
+func groupUsage(usage []byte, groups map[string]string, headers []string) []byte {
+	lines := bytes.SplitAfter(usage, []byte("\n"))
+	start := slices.IndexFunc(lines, func(line []byte) bool {
+		_, ok := usageEntry(line)
+		return ok
+	})
+	if start < 0 {
+		return usage
+	}
+
+	entries := make(map[string][]byte)
+	header := ungroupedHeader
+	end := start
+	for ; end < len(lines); end++ {
+		line := lines[end]
+		if name, ok := usageEntry(line); ok {
+			header = ungroupedHeader
+			if h, ok := groups[name]; ok {
+				header = h
+			}
+		} else if !bytes.HasPrefix(line, []byte("    \t")) {
+			break
+		}
+		entries[header] = append(entries[header], line...)
+	}
+
+	grouped := bytes.Join(lines[:start], nil)
+	for _, header := range headers {
+		if len(entries[header]) == 0 {
+			continue
+		}
+		if len(grouped) > 0 {
+			grouped = append(grouped, '\n')
+		}
+		grouped = append(grouped, header+":\n"...)
+		grouped = append(grouped, entries[header]...)
+	}
+	return append(grouped, bytes.Join(lines[end:], nil)...)
+}
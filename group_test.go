@@ -0,0 +1,49 @@
+package flagfx_test
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"testing"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestGroupUsage(t *testing.T) {
+	var out bytes.Buffer
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.SetOutput(&out)
+	fs.Bool("v", false, "verbose output")
+	fs.String("log-level", "info", "log level")
+	fs.String("log-format", "text", "log format")
+	fs.String("addr", ":8080", "address to listen on")
+	fs.Int("workers", 4, "number of workers")
+	fs.String("secret", "", "hidden secret")
+	_, err := flagfx.ParseArgs(fs, []string{"-h"},
+		flagfx.Group("Logging", "log-level"),
+		flagfx.Group("Server", "addr", "workers"),
+		flagfx.Group("Logging", "log-format"),
+		flagfx.Hidden("secret"),
+	)
+	if !errors.Is(err, flag.ErrHelp) {
+		t.Fatalf("-h: %v, want flag.ErrHelp", err)
+	}
+	checkGolden(t, "group.usage", out.Bytes())
+}
+
+func TestGroupErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []flagfx.Option
+	}{
+		{"undefined flag", []flagfx.Option{flagfx.Group("Logging", "missing")}},
+		{"two groups", []flagfx.Option{flagfx.Group("Logging", "v"), flagfx.Group("Output", "v")}},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		fs.Bool("v", false, "")
+		if _, err := flagfx.ParseArgs(fs, nil, tt.opts...); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package flagfx
+
+import "flag"
+
+// flagSet is an alias of flag.FlagSet, so that FlagSetHandle can embed it in
+// an unexported field.
+type flagSet = flag.FlagSet
+
+// FlagSetHandle gives access to the flag set parsed by flagfx for registering
+// flags. It has the methods of *flag.FlagSet, such as String, Var and Func,
+// but it can only be obtained from flagfx, so a constructor accepting a
+// FlagSetHandle is guaranteed to register its flags with the flag set of the
+// app, the one supplied via ModuleFor or FlagSet, rather than with
+// flag.CommandLine by accident:
+//
+//	flagfx.Provide(func(fs flagfx.FlagSetHandle) *flags {
+//		return &flags{Port: fs.Int("port", 8080, "port to listen on")}
+//	})
+//
+// Functions such as Define take the *flag.FlagSet, which FlagSet returns.
+//
+// Constructors accepting a *flag.FlagSet keep working unchanged; to migrate,
+// replace the parameter type and pass fs.FlagSet() wherever a *flag.FlagSet
+// is needed. Like a *flag.FlagSet, the handle refers to the flag set of the
+// subcommand inside Subcommand, and to the named set for the constructors of
+// a Named set.
+type FlagSetHandle struct {
+	*flagSet
+}
+
+// newFlagSetHandle provides the handle of the active flag set.
+func newFlagSetHandle(fs *flag.FlagSet) FlagSetHandle {
+	return FlagSetHandle{flagSet: fs}
+}
+
+// FlagSet returns the flag set, e.g. to pass it to Define.
+func (h FlagSetHandle) FlagSet() *flag.FlagSet {
+	return h.flagSet
+}
@@ -0,0 +1,96 @@
+package flagfx_test
+
+import (
+	"flag"
+	"testing"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+type handleFlags struct {
+	Port *int
+}
+
+func newHandleFlags(fs flagfx.FlagSetHandle) *handleFlags {
+	return &handleFlags{Port: fs.Int("port", 80, "port to listen on")}
+}
+
+func populatePort(t *testing.T, args []string, opts ...fx.Option) int {
+	t.Helper()
+	var f *handleFlags
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), args),
+		fx.Options(opts...),
+		fx.Populate(&f),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatalf("%q: %v", args, err)
+	}
+	return *f.Port
+}
+
+func TestFlagSetHandle(t *testing.T) {
+	var fs *flag.FlagSet
+	port := populatePort(t, []string{"-port=8080"},
+		flagfx.Provide(newHandleFlags),
+		fx.Populate(&fs),
+	)
+	if port != 8080 {
+		t.Errorf("-port = %d, want 8080", port)
+	}
+	if fs.Lookup("port") == nil {
+		t.Error("-port is not registered with the flag set of the app")
+	}
+}
+
+func TestFlagSetHandlePrefix(t *testing.T) {
+	var fs *flag.FlagSet
+	port := populatePort(t, []string{"-http.port=8080"},
+		flagfx.Prefix("http")(newHandleFlags),
+		fx.Populate(&fs),
+	)
+	if port != 8080 {
+		t.Errorf("-http.port = %d, want 8080", port)
+	}
+	if fs.Lookup("port") != nil {
+		t.Error("-port is registered without its prefix")
+	}
+}
+
+func TestFlagSetHandleNamed(t *testing.T) {
+	serverFlags, provideServer := flagfx.Named("server")
+	var fs *flag.FlagSet
+	port := populatePort(t, []string{"-port=8080"},
+		serverFlags,
+		flagfx.IgnoreUnknown(),
+		provideServer(newHandleFlags),
+		fx.Populate(&fs),
+	)
+	if port != 8080 {
+		t.Errorf("-port = %d, want 8080", port)
+	}
+	if fs.Lookup("port") != nil {
+		t.Error("-port of the named set is registered with the flag set of the app")
+	}
+}
+
+func TestFlagSetHandleSubcommand(t *testing.T) {
+	var port int
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), []string{"serve", "-port=8080"}),
+		flagfx.Subcommand("serve",
+			flagfx.Provide(newHandleFlags),
+			fx.Invoke(func(f *handleFlags) { port = *f.Port }),
+		),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if port != 8080 {
+		t.Errorf("-port = %d, want 8080", port)
+	}
+}
@@ -0,0 +1,79 @@
+package flagfx
+
+import "bytes"
+
+// Hidden returns an fx.Option that hides the named flags, e.g. operational
+// flags that would clutter the help output. Hidden flags are still parsed,
+// but are left out of the usage output, Values, Dump and the completion
+// script of CompletionFlag, unless ShowHidden is used. Naming a flag that is
+// not defined in the flag set is a configuration error.
+//
+// The flag package has no notion of hidden flags, so they are filtered from
+// whatever the usage function of the flag set writes to its output, which
+// works as long as it lists the flags in the format of PrintDefaults.
+func Hidden(names ...string) Option {
+	return addHook(stageSetup, func(p *parser) error {
+		p.hide(names...)
+		return nil
+	})
+}
+
+// ShowHidden returns an fx.Option that shows the flags hidden with Hidden as
+// if they were not, e.g. to debug a deployment.
+func ShowHidden() Option {
+	return addHook(stageSetup, func(p *parser) error {
+		p.showHidden = true
+		return nil
+	})
+}
+
+// hide marks the named flags as hidden.
+func (p *parser) hide(names ...string) {
+	if p.hidden == nil {
+		p.hidden = make(map[string]bool)
+	}
+	for _, name := range names {
+		p.hidden[name] = true
+	}
+}
+
+// isHidden reports whether the named flag is omitted from the diagnostics.
+func (p *parser) isHidden(name string) bool {
+	return p.hidden[name] && !p.showHidden
+}
+
+// wrapHiddenUsage wraps the usage function of the flag set so that the entries
+// of the hidden flags are removed from its output.
+func (p *parser) wrapHiddenUsage() error {
+	names := make([]string, 0, len(p.hidden))
+	for name := range p.hidden {
+		names = append(names, name)
+	}
+	if err := lookupAll(p.fs, "hidden", names); err != nil {
+		return err
+	}
+	if len(names) == 0 || p.showHidden {
+		return nil
+	}
+	rewriteUsage(p.fs, p.filterUsage)
+	return nil
+}
+
+// filterUsage removes the entries of hidden flags from usage output in the
+// format of PrintDefaults, where an entry starts with a line "  -name ..."
+// that is followed by indented lines holding the usage text.
+func (p *parser) filterUsage(usage []byte) []byte {
+	var filtered []byte
+	skip := false
+	for _, line := range bytes.SplitAfter(usage, []byte("\n")) {
+		if name, ok := usageEntry(line); ok {
+			skip = p.isHidden(name)
+		} else if !bytes.HasPrefix(line, []byte("    \t")) {
+			skip = false
+		}
+		if !skip {
+			filtered = append(filtered, line...)
+		}
+	}
+	return filtered
+}
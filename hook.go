@@ -0,0 +1,435 @@
+package flagfx
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/fx"
+)
+
+// stage determines when a hook runs relative to parsing.
+type stage int
+
+const (
+	// stageSetup hooks prepare the flag set before it is parsed.
+	stageSetup stage = iota
+	// stageArgs hooks rewrite the arguments before they are parsed.
+	stageArgs
+	// stageInfo hooks handle informational flags, such as -version, right
+	// after parsing. They may end the parse early, see parser.quit.
+	stageInfo
+	// stageEnv hooks supply values from the environment for flags that were
+	// not set on the command line, which therefore always takes precedence.
+	stageEnv
+	// stageFile hooks supply values from configuration files for flags
+	// that are still unset.
+	stageFile
+	// stageParsed hooks run once the values of all flags are final.
+	stageParsed
+)
+
+// hook is a step contributed by an option and executed inside the barrier.
+type hook struct {
+	stage stage
+	seq   uint64 // Orders hooks by registration, as fx shuffles value groups.
+	run   func(p *parser) error
+}
+
+// hookSeq is the sequence number of the last registered hook.
+var hookSeq atomic.Uint64
+
+// Option is an fx.Option that takes part in parsing, such as Required or
+// EnvPrefix. Besides configuring the barrier of an app, an Option can be
+// passed to ParseArgs, which runs it without an fx app.
+type Option interface {
+	fx.Option
+
+	// parseHooks returns the hooks contributed by the option.
+	parseHooks() []hook
+}
+
+// addHook returns an Option that contributes a hook to the barrier.
+// Hooks of the same stage run in the order they were registered.
+func addHook(s stage, run func(p *parser) error) Option {
+	h := hook{stage: s, seq: hookSeq.Add(1), run: run}
+	return hookOption{
+		Option: fx.Supply(fx.Annotated{Group: "flagfx_hooks", Target: h}),
+		hook:   h,
+	}
+}
+
+// hookOption is the Option returned by addHook.
+type hookOption struct {
+	fx.Option
+	hook hook
+}
+
+// parseHooks returns the hook of the option.
+func (o hookOption) parseHooks() []hook {
+	return []hook{o.hook}
+}
+
+// options combines options like fx.Options.
+func options(opts ...Option) Option {
+	fxOpts := make([]fx.Option, len(opts))
+	for i, opt := range opts {
+		fxOpts[i] = opt
+	}
+	return optionList{Option: fx.Options(fxOpts...), opts: opts}
+}
+
+// optionList is the Option returned by options.
+type optionList struct {
+	fx.Option
+	opts []Option
+}
+
+// parseHooks returns the hooks of the combined options.
+func (o optionList) parseHooks() []hook {
+	var hooks []hook
+	for _, opt := range o.opts {
+		hooks = append(hooks, opt.parseHooks()...)
+	}
+	return hooks
+}
+
+// sortHooks orders the hooks by registration.
+func sortHooks(hooks []hook) {
+	slices.SortFunc(hooks, func(a, b hook) int {
+		return cmp.Compare(a.seq, b.seq)
+	})
+}
+
+// parserParams holds the dependencies of the parser.
+type parserParams struct {
+	fx.In
+
+	FlagSet *flag.FlagSet
+	Args    Arguments
+	Env     EnvLookup
+	Warn    warningOutput
+	Exit    Exiter
+	Context parseContext
+	Owners  owners
+	Version Version
+	Hooks   []hook       `group:"flagfx_hooks"`
+	Notes   []UsageNote  `group:"flagfx_usage"`
+	Structs []FlagStruct `group:"flagfx_structs"`
+}
+
+// parser holds the state shared by the hooks of a single parse.
+type parser struct {
+	fs    *flag.FlagSet
+	args  Arguments
+	env   EnvLookup
+	warn  io.Writer
+	exit  Exiter
+	ctx   context.Context
+	hooks []hook
+
+	// version is the version of the app, see DeprecatedUntil.
+	version Version
+	// owners records the modules owning the flags, see ProvideIn.
+	owners owners
+
+	// envPrefixes holds the prefixes registered with EnvPrefix.
+	envPrefixes []string
+	// shadow receives the warnings of WarnOnShadow, if not nil.
+	shadow io.Writer
+	// hideEnvUsage omits the environment variables from the usage output.
+	hideEnvUsage bool
+	// aliases maps flags to the aliases registered with Alias, and
+	// hideAliasUsage omits them from the usage output.
+	aliases        map[string][]string
+	hideAliasUsage bool
+	// hidden records the flags hidden from the diagnostics, unless showHidden
+	// is set.
+	hidden     map[string]bool
+	showHidden bool
+	// structs holds the structs whose fields are registered as flags.
+	structs []FlagStruct
+	// notes holds the notes appended to the usage output.
+	notes []UsageNote
+	// groups holds the flag groups of the usage output, see Group.
+	groups []usageGroup
+	// suggest augments the error for an undefined flag, see SuggestUnknown.
+	suggest bool
+	// ignoreUnknown skips the flags defined by the flag sets in others, and
+	// ignoreNamed records the named flag sets doing so, see IgnoreUnknown.
+	ignoreUnknown bool
+	ignoreNamed   map[string]bool
+	others        []*flag.FlagSet
+	// tolerateUnknown drops the flags defined by no flag set, recording them
+	// in unknown, and warnUnknown warns about them, see TolerateUnknown.
+	tolerateUnknown bool
+	warnUnknown     bool
+	unknown         Unknown
+	// force makes the barrier parse a flag set that has already been parsed.
+	force bool
+	// emitJSON holds the writers registered with EmitJSON.
+	emitJSON []io.Writer
+	// dryRun holds the value of the flag registered by DryRun, and
+	// printConfig the one registered by PrintConfig.
+	dryRun      *bool
+	printConfig *bool
+	// warnings holds the warnings recorded instead of written, see
+	// WarningsAsErrors.
+	warningsAsErrors bool
+	warnings         []error
+	// deprecated records the deprecated flags that were used, and
+	// onDeprecated holds the callbacks registered with OnDeprecated.
+	deprecated   []deprecation
+	onDeprecated []func(old, new string)
+	// quitting records that an informational flag has ended the parse.
+	quitting bool
+	// helpCode is the exit code used when help is requested.
+	helpCode int
+
+	// requiredIf holds the conditionally required flags registered with
+	// RequiredIf.
+	requiredIf []requiredIf
+	// overrides holds the values registered with Override.
+	overrides []override
+	// derived holds the flags registered with DeriveDefault.
+	derived []derivedDefault
+
+	// children holds the flag sets created with Child.
+	children []*flag.FlagSet
+	// command is the selected subcommand, if any.
+	command *command
+
+	// start is the time parsing started, see OnParse.
+	start time.Time
+
+	// passThrough holds the arguments following the first "--".
+	passThrough PassThrough
+	// cli records the flags that were set on the command line.
+	cli map[string]bool
+	// set records the flags that were set on the command line or by a fallback.
+	set map[string]bool
+	// presets holds the presets defined with Preset, and preset the name of
+	// the selected one. presetConflict records a different preset selected
+	// after it, which fails the parse.
+	presets        map[string]map[string]string
+	preset         string
+	presetConflict string
+	// sources records the source of the flags that were set by a fallback.
+	sources map[string]Source
+	// locked records the flags registered at the time the flag set was
+	// locked by StrictBarrier.
+	locked map[string]bool
+}
+
+// newParser creates the parser for the active flag set and arguments.
+func newParser(params parserParams) *parser {
+	sortHooks(params.Hooks)
+	return &parser{
+		fs:      params.FlagSet,
+		args:    params.Args,
+		env:     params.Env,
+		warn:    params.Warn.w,
+		exit:    params.Exit,
+		ctx:     params.Context.ctx,
+		hooks:   params.Hooks,
+		notes:   params.Notes,
+		structs: params.Structs,
+		owners:  params.Owners,
+		version: params.Version,
+	}
+}
+
+// parseParams holds the dependencies of the barrier action.
+type parseParams struct {
+	fx.In
+
+	Parser    *parser
+	Named     []*parser  `group:"flagfx_named"`
+	Commands  []*command `group:"flagfx_commands"`
+	Lifecycle fx.Lifecycle
+}
+
+// parseAll is the barrier action. It parses the default flag set, followed by
+// the flag set of the selected subcommand and the flag sets created via Named.
+func parseAll(params parseParams) error {
+	linkParsers(append([]*parser{params.Parser}, params.Named...))
+	if _, err := params.Parser.run(); err != nil {
+		return err
+	}
+	if err := params.Parser.parseCommand(params.Commands, params.Lifecycle); err != nil {
+		return err
+	}
+	if err := params.Parser.ignoreUnknownNamed(params.Named); err != nil {
+		return err
+	}
+	for _, p := range params.Named {
+		if _, err := p.run(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parse parses a single flag set. It runs the setup hooks, parses the arguments
+// and then runs the hooks that need the parsed flag set. A flag set that has
+// already been parsed elsewhere is left as is, unless ForceParse is used.
+func (p *parser) parse() error {
+	p.start = time.Now()
+	if err := p.bindStructs(); err != nil {
+		return err
+	}
+	if err := p.runHooks(stageSetup); err != nil {
+		return err
+	}
+	if err := p.wrapUsage(); err != nil {
+		return err
+	}
+	if p.fs.Parsed() && !p.force {
+		p.passThrough = PassThrough{}
+		p.cli = setFlags(p.fs)
+		p.set = setFlags(p.fs)
+		return nil
+	}
+	if err := p.runHooks(stageArgs); err != nil {
+		return err
+	}
+	if err := p.checkContext(); err != nil {
+		return err
+	}
+	args, passThrough := splitPassThrough(p.args)
+	p.passThrough = passThrough
+	args, childArgs := p.routeChildFlags(args)
+	if p.ignoreUnknown || p.tolerateUnknown {
+		args = p.skipUnknown(args)
+	}
+	if err := p.fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			// The usage has been printed, so asking for help is not a failure.
+			p.exit(p.helpCode)
+		}
+		if p.suggest {
+			err = p.withSuggestion(err)
+		}
+		return ParseError{Args: p.args, Err: err}
+	}
+	for i, child := range p.children {
+		if err := child.Parse(childArgs[i]); err != nil {
+			return ParseError{Args: p.args, Err: err}
+		}
+	}
+	p.cli = setFlags(p.fs)
+	p.set = setFlags(p.fs)
+	if err := p.runHooks(stageInfo); err != nil || p.quitting {
+		return err
+	}
+	if err := p.applyPreset(); err != nil {
+		return err
+	}
+	for _, s := range []stage{stageEnv, stageFile} {
+		if err := p.runHooks(s); err != nil {
+			return err
+		}
+	}
+	if err := p.applyOverrides(); err != nil {
+		return err
+	}
+	if err := p.applyDerived(); err != nil {
+		return err
+	}
+	if err := p.notifyDeprecated(); err != nil {
+		return err
+	}
+	if err := p.printEffective(); err != nil || p.quitting {
+		return err
+	}
+	if err := p.checkRequiredIf(); err != nil {
+		return err
+	}
+	if err := p.runHooks(stageParsed); err != nil {
+		return err
+	}
+	if err := errors.Join(p.warnings...); err != nil {
+		return err
+	}
+	if err := p.writeJSON(); err != nil {
+		return err
+	}
+	p.printDryRun()
+	return nil
+}
+
+// setFallback sets the named flag to a value supplied by a fallback, such as
+// an environment variable, and records that the flag now has a value and
+// where it came from.
+func (p *parser) setFallback(name, value string, src Source) error {
+	if err := p.fs.Set(name, value); err != nil {
+		return err
+	}
+	p.set[name] = true
+	if p.sources == nil {
+		p.sources = make(map[string]Source)
+	}
+	p.sources[name] = src
+	return nil
+}
+
+// quit ends the parse cleanly after an informational flag has been handled:
+// it exits with code 0 and, should the Exiter return, e.g. in tests, skips the
+// remaining hooks, fallbacks and checks, so the app is not failed by flags
+// that are irrelevant to the request, such as a missing required flag.
+func (p *parser) quit() {
+	p.quitting = true
+	p.exit(0)
+}
+
+// warnf writes a warning to the warning output.
+func (p *parser) warnf(format string, args ...any) {
+	p.warnTo(p.warn, format, args...)
+}
+
+// warnTo writes a warning to w, or records it to fail the parse with if
+// WarningsAsErrors is used.
+func (p *parser) warnTo(w io.Writer, format string, args ...any) {
+	if p.warningsAsErrors {
+		p.warnings = append(p.warnings, fmt.Errorf("flagfx: "+format, args...))
+		return
+	}
+	fmt.Fprintf(w, "flagfx: "+format+"\n", args...)
+}
+
+// runHooks runs the hooks of the given stage, stopping at the first error,
+// once the context of the barrier is done or once a hook has called quit.
+func (p *parser) runHooks(s stage) error {
+	for _, h := range p.hooks {
+		if h.stage != s {
+			continue
+		}
+		if p.quitting {
+			return nil
+		}
+		if err := p.checkContext(); err != nil {
+			return err
+		}
+		if err := h.run(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitPassThrough splits the arguments at the first standalone "--",
+// returning the arguments before it and the ones following it.
+func splitPassThrough(args Arguments) (Arguments, PassThrough) {
+	for i, arg := range args {
+		if arg == "--" {
+			return args[:i], append(PassThrough{}, args[i+1:]...)
+		}
+	}
+	return args, PassThrough{}
+}
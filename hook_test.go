@@ -0,0 +1,79 @@
+package flagfx_test
+
+import (
+	"bytes"
+	"flag"
+	"sync"
+	"testing"
+
+	"github.com/lftk/flagfx"
+)
+
+// TestOptionSharedByParsers checks that an option keeps no state of its own
+// between parses, so that one option value can serve several parsers at once.
+func TestOptionSharedByParsers(t *testing.T) {
+	completion := flagfx.CompletionFlag("completion")
+	version := flagfx.PrintAndExit("version", "print the version", func() string { return "" })
+	deprecated := flagfx.Deprecated("loglevel", "log-level")
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var out bytes.Buffer
+			fs := flag.NewFlagSet("app", flag.ContinueOnError)
+			fs.SetOutput(&out)
+			fs.String("log-level", "info", "")
+			args := []string{"-log-level=debug"}
+			if i%2 == 0 {
+				args = []string{"-completion=bash", "-loglevel=debug"}
+			}
+			if _, err := flagfx.ParseArgs(fs, args, completion, version, deprecated); err != nil {
+				t.Errorf("%q: %v", args, err)
+				return
+			}
+			if got, want := out.Len() > 0, i%2 == 0; got != want {
+				t.Errorf("%q: completion written %v, want %v", args, got, want)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// TestOptionParseTwice checks that the options registering a flag of their
+// own can parse the same flag set again, as with ForceParse, without
+// registering the flag twice.
+func TestOptionParseTwice(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  flagfx.Option
+		arg  string // Given in the first parse only.
+		flag string // Reset to its default by the second parse, if not empty.
+	}{
+		{"Alias", flagfx.Alias("quiet", "q"), "-q", ""},
+		{"Deprecated", flagfx.Deprecated("loglevel", "log-level"), "-loglevel=debug", ""},
+		{"Preset", flagfx.Preset("dev", map[string]string{"log-level": "debug"}), "-preset=dev", "preset"},
+		{"PrintAndExit", flagfx.PrintAndExit("version", "print the version", func() string { return "v1" }), "-version", "version"},
+		{"DryRun", flagfx.DryRun(), "-flagfx-dry-run", "flagfx-dry-run"},
+		{"PrintConfig", flagfx.PrintConfig(), "-flagfx-print-config", "flagfx-print-config"},
+		{"ConfigFileFlag", flagfx.ConfigFileFlag("config", "config file"), "-config=" + writeConfig(t, "quiet=true\n"), "config"},
+		{"CompletionFlag", flagfx.CompletionFlag("completion"), "-completion=bash", "completion"},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		fs.Bool("quiet", false, "")
+		fs.String("log-level", "info", "")
+		for i, args := range [][]string{{tt.arg}, nil} {
+			if _, err := flagfx.ParseArgs(fs, args, flagfx.ForceParse(), tt.opt); err != nil {
+				t.Errorf("%s: parse %d: %v", tt.name, i+1, err)
+			}
+		}
+		if tt.flag == "" {
+			continue
+		}
+		if f := fs.Lookup(tt.flag); f.Value.String() != f.DefValue {
+			t.Errorf("%s: -%s = %q after the second parse, want %q", tt.name, tt.flag, f.Value, f.DefValue)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package flagfx
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// IgnoreUnknown returns an fx.Option that makes the named flag sets created
+// with Named skip the flags they don't define but another flag set of the app
+// does, instead of rejecting them. Without names, it applies to the default
+// flag set.
+//
+// Named flag sets are parsed against the same Arguments as the default flag
+// set, so by default each set must accept every flag given on the command
+// line. To let several sets split the command line between them, use
+// IgnoreUnknown for all of them:
+//
+//	serverFlags, provideServer := flagfx.Named("server")
+//	clientFlags, provideClient := flagfx.Named("client")
+//	fx.New(
+//		flagfx.Module, serverFlags, clientFlags,
+//		flagfx.IgnoreUnknown(),
+//		flagfx.IgnoreUnknown("server", "client"),
+//		...
+//	)
+//
+// A flag defined by no flag set is still rejected, so typos keep failing the
+// app, unless TolerateUnknown is used. Naming a flag set that is not part of
+// the app is a configuration error.
+func IgnoreUnknown(names ...string) Option {
+	return addHook(stageSetup, func(p *parser) error {
+		if len(names) == 0 {
+			p.ignoreUnknown = true
+			return nil
+		}
+		if p.ignoreNamed == nil {
+			p.ignoreNamed = make(map[string]bool)
+		}
+		for _, name := range names {
+			p.ignoreNamed[name] = true
+		}
+		return nil
+	})
+}
+
+// TolerateUnknown returns an fx.Option that drops the flags defined by no flag
+// set of the app from Arguments instead of failing the app, for rolling
+// deployments that pass flags only a newer binary understands. The dropped flags
+// are recorded in Unknown and, if warn is true, reported on the warning output:
+//
+//	flagfx: ignoring unknown flag -new-feature
+//
+// The known flags following an unknown one are parsed as usual. Since it is
+// unknown whether an unknown flag takes a value, it should be given as
+// -name=value; a value given as the next argument is taken as the first
+// positional argument, which ends the flags like any other. Typos are dropped
+// as well, so the option is opt-in.
+//
+// The option applies to the named flag sets and the selected Subcommand as
+// well. The named sets parse the same Arguments, so only the default flag set
+// records and reports the flags dropped, while the Unknown of a subcommand
+// holds the flags dropped from its own arguments.
+func TolerateUnknown(warn bool) Option {
+	return addHook(stageSetup, func(p *parser) error {
+		p.tolerateUnknown = true
+		p.warnUnknown = warn
+		return nil
+	})
+}
+
+// Unknown holds the flags dropped by TolerateUnknown, with their dashes and
+// values as given, e.g. -new-feature=on. It is never nil.
+//
+// Like Positional, Unknown must be consumed via fx.Provide or fx.Invoke.
+type Unknown []string
+
+// linkParsers tells each of the parsers about the flag sets of the others.
+func linkParsers(parsers []*parser) {
+	for _, p := range parsers {
+		for _, other := range parsers {
+			if other != p {
+				p.others = append(p.others, other.fs)
+			}
+		}
+	}
+}
+
+// ignoreUnknownNamed applies IgnoreUnknown and TolerateUnknown, as registered
+// with the default parser, to the parsers of the named flag sets.
+func (p *parser) ignoreUnknownNamed(named []*parser) error {
+	found := make(map[string]bool)
+	for _, q := range named {
+		q.tolerateUnknown = p.tolerateUnknown
+		if p.ignoreNamed[q.fs.Name()] {
+			q.ignoreUnknown = true
+			found[q.fs.Name()] = true
+		}
+	}
+	for name := range p.ignoreNamed {
+		if !found[name] {
+			return fmt.Errorf("flagfx: flag set %q of IgnoreUnknown is not defined", name)
+		}
+	}
+	return nil
+}
+
+// skipUnknown removes the flags that the flag set of the parser does not
+// define from args, along with their values: the ones defined by one of the
+// other flag sets with IgnoreUnknown, and the ones defined by no flag set with
+// TolerateUnknown, which are recorded in p.unknown. Otherwise, it stops at the
+// first flag defined by no flag set, leaving it to the flag package to report.
+func (p *parser) skipUnknown(args Arguments) Arguments {
+	var rest Arguments
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if len(arg) < 2 || arg[0] != '-' || arg == "--" {
+			// The flag package stops parsing at the first non-flag argument.
+			return append(rest, args[i:]...)
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		f, owned := p.fs.Lookup(name), true
+		if f == nil && name != "h" && name != "help" {
+			var other *flag.Flag
+			for _, fs := range p.others {
+				if other = fs.Lookup(name); other != nil {
+					break
+				}
+			}
+			switch {
+			case other != nil && p.ignoreUnknown:
+				f, owned = other, false
+			case other == nil && p.tolerateUnknown:
+				if p.warnUnknown {
+					p.warnf("ignoring unknown flag -%s", name)
+				}
+				p.unknown = append(p.unknown, arg)
+				continue
+			default:
+				return append(rest, args[i:]...)
+			}
+		}
+		n := 1
+		if f != nil && !hasValue && !isBoolFlag(f) && i+1 < len(args) {
+			n = 2 // The value of the flag follows.
+		}
+		if owned {
+			rest = append(rest, args[i:i+n]...)
+		}
+		i += n - 1
+	}
+	return rest
+}
@@ -0,0 +1,124 @@
+package flagfx_test
+
+import (
+	"bytes"
+	"flag"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestTolerateUnknown(t *testing.T) {
+	tests := []struct {
+		args       []string
+		unknown    []string
+		positional []string
+	}{
+		{[]string{"-port=1"}, nil, nil},
+		{[]string{"-new=on", "-port=1", "x"}, []string{"-new=on"}, []string{"x"}},
+		{[]string{"--new", "-port=1"}, []string{"--new"}, nil},
+		// The value of an unknown flag given as the next argument is the
+		// first positional argument, which ends the flags.
+		{[]string{"-new", "on", "-port=1"}, []string{"-new"}, []string{"on", "-port=1"}},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		fs.Int("port", 0, "")
+		res, err := flagfx.ParseArgs(fs, tt.args, flagfx.TolerateUnknown(false))
+		if err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if !slicesEqual(res.Unknown, tt.unknown) {
+			t.Errorf("%q: Unknown = %q, want %q", tt.args, res.Unknown, tt.unknown)
+		}
+		if !slicesEqual(res.Positional, tt.positional) {
+			t.Errorf("%q: Positional = %q, want %q", tt.args, res.Positional, tt.positional)
+		}
+	}
+}
+
+func slicesEqual[S ~[]string](got S, want []string) bool {
+	return len(got) == 0 && len(want) == 0 || reflect.DeepEqual([]string(got), want)
+}
+
+func TestTolerateUnknownWarns(t *testing.T) {
+	var warnings bytes.Buffer
+	var unknown flagfx.Unknown
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), []string{"-new=on"}),
+		flagfx.TolerateUnknown(true),
+		flagfx.WarningOutput(&warnings),
+		fx.Populate(&unknown),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !slicesEqual(unknown, []string{"-new=on"}) {
+		t.Errorf("Unknown = %q", unknown)
+	}
+	if got := warnings.String(); strings.Count(got, "ignoring unknown flag -new") != 1 {
+		t.Errorf("warnings = %q, want a single one", got)
+	}
+}
+
+func TestTolerateUnknownNamed(t *testing.T) {
+	serverFlags, provideServer := flagfx.Named("server")
+	var port *int
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), []string{"-new=on", "-port=8080"}),
+		serverFlags,
+		flagfx.IgnoreUnknown(),
+		flagfx.TolerateUnknown(false),
+		provideServer(func(fs *flag.FlagSet) *int { return fs.Int("port", 80, "") }),
+		fx.Populate(&port),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if *port != 8080 {
+		t.Errorf("-port = %d, want 8080", *port)
+	}
+}
+
+func TestTolerateUnknownSubcommand(t *testing.T) {
+	var unknown flagfx.Unknown
+	var port *int
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), []string{"serve", "-new=on", "-port=8080"}),
+		flagfx.TolerateUnknown(false),
+		flagfx.Subcommand("serve",
+			flagfx.Provide(func(fs *flag.FlagSet) *int { return fs.Int("port", 80, "") }),
+			fx.Invoke(func(u flagfx.Unknown, p *int) { unknown, port = u, p }),
+		),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if *port != 8080 || !slicesEqual(unknown, []string{"-new=on"}) {
+		t.Errorf("-port = %d, Unknown = %q", *port, unknown)
+	}
+}
+
+func TestIgnoreUnknownRejectsTypos(t *testing.T) {
+	serverFlags, provideServer := flagfx.Named("server")
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), []string{"-prot=8080"}),
+		serverFlags,
+		flagfx.IgnoreUnknown(),
+		flagfx.IgnoreUnknown("server"),
+		provideServer(func(fs *flag.FlagSet) *int { return fs.Int("port", 80, "") }),
+		fx.Invoke(func(*int) {}),
+	)
+	if app.Err() == nil {
+		t.Error("-prot: app built without error")
+	}
+}
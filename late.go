@@ -0,0 +1,167 @@
+package flagfx
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"reflect"
+
+	"go.uber.org/fx"
+)
+
+// LateFlagSet is the flag set of the second phase of parsing, see LatePhase.
+// Constructors passed to LateProvide register their flags with it.
+type LateFlagSet struct {
+	*flag.FlagSet
+}
+
+// LateReady signals that the second phase of parsing has completed, like Ready
+// does for the first one. Depending on it orders a constructor or invoke after
+// the flags registered in the second phase are parsed.
+//
+// Like Positional, LateReady must be consumed via fx.Provide or fx.Invoke.
+type LateReady struct{}
+
+// LatePhase returns an fx.Option that adds a second phase of parsing, for
+// plugins that are loaded once the flags of the app are parsed and register
+// flags of their own. The first phase drops the flags that no flag set defines,
+// as TolerateUnknown does without warning, and the second phase parses them
+// against the LateFlagSet, once the constructors passed to LateProvide have
+// registered their flags:
+//
+//	fx.New(
+//		flagfx.Module,
+//		flagfx.LatePhase(),
+//		flagfx.Provide(newFlags), // -plugins=a,b
+//		flagfx.LateProvide(func(f *flags, fs flagfx.LateFlagSet) *plugins {
+//			return loadPlugins(f.Plugins, fs) // Registers -plugin.opt.
+//		}),
+//		fx.Invoke(func(p *plugins) { ... }),
+//	)
+//
+// Constructors passed to LateProvide may depend on the results of the first
+// phase, and their results are only handed out once the second phase has
+// parsed their flags. A flag that is unknown to the second phase as well fails
+// the app with a ParseError. Since it is unknown in the first phase whether a
+// late flag takes a value, late flags must be given as -name=value: given as
+// -name value, the value ends the flags of the first phase as a positional
+// argument, and the second phase fails for lack of a value.
+//
+// The usage output of the first phase does not list the late flags.
+func LatePhase() fx.Option {
+	return fx.Options(
+		addHook(stageSetup, func(p *parser) error {
+			p.tolerateUnknown = true
+			return nil
+		}),
+		fx.Provide(
+			newLateFlagSet,
+			fx.Annotate(newLateBarrier, fx.ParamTags(`group:"flagfx_late"`)),
+			func(*lateBarrier) LateReady { return LateReady{} },
+		),
+	)
+}
+
+// LateProvide is like Provide, but for the second phase of parsing added with
+// LatePhase: the constructors register their flags with the LateFlagSet, and
+// their results are handed out once it has been parsed.
+func LateProvide(constructors ...any) fx.Option {
+	var opts []fx.Option
+	for _, c := range constructors {
+		if a, ok := c.(fx.Annotated); ok {
+			c = annotateResults(a)
+		}
+		fv := reflect.ValueOf(catchRedefined(c))
+		if fv.Kind() != reflect.Func {
+			opts = append(opts, fx.Error(fmt.Errorf("flagfx: LateProvide expects a function, got %T", c)))
+			continue
+		}
+		opts = append(opts, provideLate(fv))
+	}
+	return fx.Options(opts...)
+}
+
+// lateEntry is a constructor of the second phase, with the dependencies it is
+// called with.
+type lateEntry struct {
+	fn   reflect.Value
+	args []reflect.Value
+}
+
+// Pre-calculated reflection types for efficiency.
+var (
+	_reflLateEntryPtr   = reflect.TypeFor[*lateEntry]()
+	_reflLateBarrierPtr = reflect.TypeFor[*lateBarrier]()
+)
+
+// provideLate splits the constructor fn, whose last result is an error, in
+// two: one capturing its dependencies into a lateEntry of the "flagfx_late"
+// group, and one returning its results from the lateBarrier, which calls it.
+func provideLate(fn reflect.Value) fx.Option {
+	ft := fn.Type()
+	e := &lateEntry{fn: fn}
+
+	var in, out []reflect.Type
+	for i := range ft.NumIn() {
+		in = append(in, ft.In(i))
+	}
+	for i := range ft.NumOut() {
+		out = append(out, ft.Out(i))
+	}
+	capture := reflect.MakeFunc(
+		reflect.FuncOf(in, []reflect.Type{_reflLateEntryPtr}, ft.IsVariadic()),
+		func(args []reflect.Value) []reflect.Value {
+			e.args = args
+			return []reflect.Value{reflect.ValueOf(e)}
+		},
+	)
+	results := reflect.MakeFunc(
+		reflect.FuncOf([]reflect.Type{_reflLateBarrierPtr}, out, false),
+		func(args []reflect.Value) []reflect.Value {
+			return args[0].Interface().(*lateBarrier).results[e]
+		},
+	)
+	return fx.Options(
+		fx.Provide(fx.Annotate(capture.Interface(), fx.ResultTags(`group:"flagfx_late"`))),
+		fx.Provide(results.Interface()),
+	)
+}
+
+// lateBarrier holds the results of the constructors of the second phase.
+type lateBarrier struct {
+	results map[*lateEntry][]reflect.Value
+}
+
+// newLateBarrier is the second barrier. It calls the constructors of the
+// second phase, which register their flags, and then parses the flags dropped
+// by the first phase against the late flag set.
+func newLateBarrier(entries []*lateEntry, late LateFlagSet, r result) (*lateBarrier, error) {
+	b := &lateBarrier{results: make(map[*lateEntry][]reflect.Value)}
+	for _, e := range entries {
+		var results []reflect.Value
+		if e.fn.Type().IsVariadic() {
+			results = e.fn.CallSlice(e.args)
+		} else {
+			results = e.fn.Call(e.args)
+		}
+		if err, _ := results[len(results)-1].Interface().(error); err != nil {
+			return nil, err
+		}
+		b.results[e] = results
+	}
+	if err := late.Parse(r.p.unknown); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			r.p.exit(r.p.helpCode)
+		}
+		return nil, ParseError{Args: r.p.args, Err: err}
+	}
+	return b, nil
+}
+
+// newLateFlagSet provides the flag set of the second phase, which shares the
+// name, error handling and output of the active flag set.
+func newLateFlagSet(fs *flag.FlagSet) LateFlagSet {
+	late := flag.NewFlagSet(fs.Name(), fs.ErrorHandling())
+	late.SetOutput(fs.Output())
+	return LateFlagSet{FlagSet: late}
+}
@@ -0,0 +1,78 @@
+package flagfx_test
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+type pluginFlags struct {
+	Opt *string
+}
+
+func newLateApp(args ...string) (*fx.App, *pluginFlags) {
+	var plugin *pluginFlags
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), args),
+		flagfx.LatePhase(),
+		flagfx.LateProvide(func(fs flagfx.LateFlagSet) (*pluginFlags, error) {
+			fs.SetOutput(newTestFlagSet().Output())
+			return &pluginFlags{Opt: fs.String("plugin.opt", "default", "")}, nil
+		}),
+		fx.Populate(&plugin),
+	)
+	return app, plugin
+}
+
+func TestLatePhase(t *testing.T) {
+	var (
+		p      *portFlag
+		plugin *pluginFlags
+	)
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), []string{"-plugin.opt=x", "-port=8080"}),
+		flagfx.LatePhase(),
+		flagfx.Provide(func(fs *flag.FlagSet) *portFlag { return &portFlag{fs.Int("port", 80, "")} }),
+		// The plugin depends on the first phase and registers its flag
+		// for the second one.
+		flagfx.LateProvide(func(p *portFlag, fs flagfx.LateFlagSet) *pluginFlags {
+			if *p.port != 8080 {
+				t.Errorf("plugin loaded with -port=%d, want the first phase parsed", *p.port)
+			}
+			return &pluginFlags{Opt: fs.String("plugin.opt", "default", "")}
+		}),
+		fx.Invoke(func(flagfx.LateReady) {}),
+		fx.Populate(&p, &plugin),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if *p.port != 8080 || *plugin.Opt != "x" {
+		t.Errorf("-port=%d -plugin.opt=%s, want 8080 and x", *p.port, *plugin.Opt)
+	}
+}
+
+func TestLatePhaseUnknownFlag(t *testing.T) {
+	app, _ := newLateApp("-plugin.opt=x", "-other=y")
+	var perr flagfx.ParseError
+	if err := app.Err(); !errors.As(err, &perr) || !strings.Contains(err.Error(), "-other") {
+		t.Errorf("error %v, want a ParseError naming -other", err)
+	}
+}
+
+func TestLatePhaseTwoTokenFlag(t *testing.T) {
+	// The first phase cannot know that -plugin.opt takes a value, so the
+	// value given as the next argument ends the flags as a positional
+	// argument, and the second phase finds -plugin.opt without its value.
+	app, _ := newLateApp("-plugin.opt", "x")
+	if app.Err() == nil {
+		t.Error("-plugin.opt x: app built without error")
+	}
+}
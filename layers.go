@@ -0,0 +1,91 @@
+package flagfx
+
+import "flag"
+
+// Source identifies the layer that supplied the value of a flag.
+type Source int
+
+const (
+	// SourceDefault is the default value of the flag.
+	SourceDefault Source = iota
+	// SourceConfig is a configuration file or source, see ConfigFile and
+	// ConfigSourceContext.
+	SourceConfig
+	// SourceEnv is an environment variable, see EnvPrefix.
+	SourceEnv
+	// SourcePreset is the preset selected with -preset, see Preset.
+	SourcePreset
+	// SourceCommandLine is the command line.
+	SourceCommandLine
+	// SourceOverride is an override applied with Override.
+	SourceOverride
+)
+
+// String returns the name of the source.
+func (s Source) String() string {
+	switch s {
+	case SourceDefault:
+		return "default"
+	case SourceConfig:
+		return "config"
+	case SourceEnv:
+		return "env"
+	case SourcePreset:
+		return "preset"
+	case SourceCommandLine:
+		return "command line"
+	case SourceOverride:
+		return "override"
+	default:
+		return "unknown"
+	}
+}
+
+// Sources maps the name of every flag in the flag set to the layer that
+// supplied its effective value, e.g. to log where the configuration came from.
+// Flags passed to Hidden are left out, unless ShowHidden is used.
+//
+// Like Positional, Sources must be consumed via fx.Provide or fx.Invoke.
+type Sources map[string]Source
+
+// Provenance is another name for Sources, for auditing where the configuration
+// came from at startup. Each layer records itself as it applies a value, so
+// the source of a flag is the last layer that wrote it; as the layers above
+// always win, a value given in a config file, the environment and on the
+// command line is reported as SourceCommandLine.
+type Provenance = Sources
+
+// newSources records the source of the value of every flag in the flag set.
+func (p *parser) newSources() Sources {
+	sources := make(Sources)
+	p.fs.VisitAll(func(f *flag.Flag) {
+		if p.isHidden(f.Name) {
+			return
+		}
+		if p.cli[f.Name] && p.sources[f.Name] != SourceOverride {
+			sources[f.Name] = SourceCommandLine
+		} else {
+			sources[f.Name] = p.sources[f.Name]
+		}
+	})
+	return sources
+}
+
+// Layers returns an fx.Option combining the given layers of configuration,
+// such as ConfigFile and EnvPrefix, into the layered configuration
+//
+//	command line > environment > config file > flag default
+//
+// where each layer only supplies the flags left unset by the layers above it.
+// The precedence is enforced whatever the order of the layers: the command
+// line is parsed first, then the environment and the config files are applied
+// to the flags that are still unset. Sources records which layer supplied
+// each flag.
+//
+//	flagfx.Layers(flagfx.ConfigFile("app.conf"), flagfx.EnvPrefix("APP"))
+//
+// The layers may also be added to the app individually, with the same effect;
+// Layers documents their relationship at the call site.
+func Layers(layers ...Option) Option {
+	return options(layers...)
+}
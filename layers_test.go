@@ -0,0 +1,58 @@
+package flagfx_test
+
+import (
+	"testing"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestProvenance(t *testing.T) {
+	env := map[string]string{"APP_LEVEL": "env", "APP_HOST": "env"}
+	tests := []struct {
+		args  []string
+		level string
+		want  flagfx.Provenance
+	}{
+		{
+			[]string{"-level=cli"}, "cli",
+			flagfx.Provenance{"level": flagfx.SourceCommandLine, "host": flagfx.SourceEnv, "port": flagfx.SourceConfig, "user": flagfx.SourceDefault},
+		},
+		{
+			nil, "env",
+			flagfx.Provenance{"level": flagfx.SourceEnv, "host": flagfx.SourceEnv, "port": flagfx.SourceConfig, "user": flagfx.SourceDefault},
+		},
+	}
+	path := writeConfig(t, "level=file\nhost=file\nport=8080\n")
+	for _, tt := range tests {
+		var provenance flagfx.Provenance
+		fs := newTestFlagSet()
+		level := fs.String("level", "default", "")
+		fs.String("host", "localhost", "")
+		fs.Int("port", 80, "")
+		fs.String("user", "", "")
+		app := fx.New(
+			fx.NopLogger,
+			flagfx.ModuleFor(fs, tt.args),
+			flagfx.Env(func(key string) (string, bool) {
+				v, ok := env[key]
+				return v, ok
+			}),
+			flagfx.Layers(flagfx.ConfigFile(path), flagfx.EnvPrefix("APP")),
+			fx.Populate(&provenance),
+		)
+		if err := app.Err(); err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		for name, want := range tt.want {
+			if got := provenance[name]; got != want {
+				t.Errorf("%q: Provenance[%s] = %v, want %v", tt.args, name, got, want)
+			}
+		}
+		if *level != tt.level {
+			t.Errorf("%q: -level = %s, want %s", tt.args, *level, tt.level)
+		}
+	}
+}
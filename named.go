@@ -0,0 +1,124 @@
+package flagfx
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"go.uber.org/fx"
+)
+
+// ProvideFunc is the signature of Provide. Named returns a ProvideFunc bound
+// to a named flag set.
+type ProvideFunc func(constructors ...any) fx.Option
+
+// Named creates an isolated flag set. It returns the fx.Option setting up the
+// flag set, and a ProvideFunc that selects it: constructors passed to the
+// ProvideFunc receive the named flag set wherever they accept a *flag.FlagSet
+// or a FlagSetHandle, and are deferred until it has been parsed.
+//
+//	serverFlags, provideServer := flagfx.Named("server")
+//	fx.New(
+//		flagfx.Module, serverFlags,
+//		provideServer(func(fs *flag.FlagSet) *config { ... }),
+//	)
+//
+// Flags of different named sets may share names without panicking. Named
+// sets are parsed by the flagfx barrier right after the default flag set,
+// against the same Arguments, so every set must accept all the flags given on
+// the command line, unless IgnoreUnknown lets the sets skip each other's
+// flags. Named sets use flag.ContinueOnError and require Module to
+// be part of the app.
+//
+// The options of this package that hook into parsing, such as EnvPrefix,
+// ConfigFile, Required, Validate or Deprecated, apply to the default flag set
+// only, as do the informational flags such as -version. A named set is parsed
+// as given on the command line, with its flags keeping their defaults
+// otherwise; only IgnoreUnknown and TolerateUnknown take named sets into
+// account.
+func Named(name string) (fx.Option, ProvideFunc) {
+	tag := fmt.Sprintf(`name:"flagfx.%s"`, name)
+
+	opt := fx.Module("flagfx."+name,
+		fx.Provide(
+			fx.Annotate(
+				func() *flag.FlagSet {
+					return flag.NewFlagSet(name, flag.ContinueOnError)
+				},
+				fx.ResultTags(tag),
+			),
+			fx.Annotate(newFlagSetHandle, fx.ParamTags(tag), fx.ResultTags(tag)),
+			// Contribute the parser of the named set to the flagfx barrier.
+			fx.Annotate(
+				func(fs *flag.FlagSet, args Arguments, env EnvLookup, warn warningOutput, exit Exiter, ctx parseContext) *parser {
+					return &parser{fs: fs, args: args, env: env, warn: warn.w, exit: exit, ctx: ctx.ctx}
+				},
+				fx.ParamTags(tag), fx.ResultTags(`group:"flagfx_named"`),
+			),
+		),
+	)
+
+	provide := func(constructors ...any) fx.Option {
+		tagged := make([]any, len(constructors))
+		for i, c := range constructors {
+			tagged[i] = tagParams(c, tag, _reflFlagSetPtr, _reflFlagSetHandle)
+		}
+		return Provide(tagged...)
+	}
+	return opt, provide
+}
+
+// Pre-calculated reflection types for efficiency.
+var (
+	_reflIn            = reflect.TypeFor[fx.In]()
+	_reflFlagSetPtr    = reflect.TypeFor[*flag.FlagSet]()
+	_reflFlagSetHandle = reflect.TypeFor[FlagSetHandle]()
+)
+
+// tagParams wraps the function fn so that its parameters of any of the types
+// are resolved with the given tag, e.g. `name:"..."`, instead of by type alone.
+// The wrapper takes a single fx.In struct holding all of fn's parameters.
+// Values that are not functions, such as fx.Annotated, are returned as is.
+func tagParams(fn any, tag string, types ...reflect.Type) any {
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func {
+		return fn
+	}
+
+	ft := fv.Type()
+	fields := []reflect.StructField{
+		{Name: "In", Type: _reflIn, Anonymous: true},
+	}
+	for i := range ft.NumIn() {
+		field := reflect.StructField{
+			Name: fmt.Sprintf("P%d", i),
+			Type: ft.In(i),
+		}
+		if slices.Contains(types, field.Type) {
+			field.Tag = reflect.StructTag(tag)
+		}
+		fields = append(fields, field)
+	}
+	var out []reflect.Type
+	for i := range ft.NumOut() {
+		out = append(out, ft.Out(i))
+	}
+
+	in := []reflect.Type{reflect.StructOf(fields)}
+	wrapper := reflect.MakeFunc(
+		reflect.FuncOf(in, out, false),
+		func(args []reflect.Value) []reflect.Value {
+			params := make([]reflect.Value, ft.NumIn())
+			for i := range params {
+				// Skip the embedded fx.In field.
+				params[i] = args[0].Field(i + 1)
+			}
+			if ft.IsVariadic() {
+				return fv.CallSlice(params)
+			}
+			return fv.Call(params)
+		},
+	)
+	return wrapper.Interface()
+}
@@ -0,0 +1,38 @@
+package flagfx_test
+
+import (
+	"flag"
+	"testing"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestNamedSkipsHooks(t *testing.T) {
+	serverFlags, provideServer := flagfx.Named("server")
+	var port *int
+	fs := newTestFlagSet()
+	level := fs.String("log-level", "info", "")
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(fs, nil),
+		serverFlags,
+		flagfx.Env(func(key string) (string, bool) {
+			v, ok := map[string]string{"APP_LOG_LEVEL": "debug", "APP_PORT": "8080"}[key]
+			return v, ok
+		}),
+		flagfx.EnvPrefix("app"),
+		provideServer(func(fs *flag.FlagSet) *int { return fs.Int("port", 80, "") }),
+		fx.Populate(&port),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if *level != "debug" {
+		t.Errorf("-log-level = %s, want the environment applied to the default set", *level)
+	}
+	if *port != 80 {
+		t.Errorf("-port = %d, want the default of the named set", *port)
+	}
+}
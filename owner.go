@@ -0,0 +1,121 @@
+package flagfx
+
+import (
+	"flag"
+	"reflect"
+
+	"go.uber.org/fx"
+)
+
+// Ownership maps the name of every flag registered by a constructor passed to
+// ProvideIn to the module that registered it, answering questions such as
+// which module defined -port. Flags registered otherwise are left out, and so
+// are flags passed to Hidden, unless ShowHidden is used.
+//
+// Like Positional, Ownership must be consumed via fx.Provide or fx.Invoke.
+type Ownership map[string]string
+
+// ProvideIn is like Provide, but records the flags registered by the
+// constructors as owned by the named module, see Ownership. fx does not
+// expose the module a constructor belongs to, so the name is given
+// explicitly, typically the name of the enclosing fx.Module:
+//
+//	var Module = fx.Module("logfx",
+//		flagfx.ProvideIn("logfx", newFlags),
+//	)
+func ProvideIn(module string, constructors ...any) fx.Option {
+	owned := make([]any, len(constructors))
+	for i, c := range constructors {
+		if a, ok := c.(fx.Annotated); ok {
+			a.Target = recordOwner(a.Target, module)
+			c = a
+		} else {
+			c = recordOwner(c, module)
+		}
+		owned[i] = c
+	}
+	return Provide(owned...)
+}
+
+// owners records the module owning each flag, per flag set. It is shared by
+// the constructors of an app.
+type owners map[*flag.FlagSet]map[string]string
+
+// newOwners provides the owners of the flags of an app.
+func newOwners() owners {
+	return make(owners)
+}
+
+// ownership returns the owners of the flags of the flag set, leaving out the
+// flags for which hidden reports true.
+func (o owners) ownership(fs *flag.FlagSet, hidden func(name string) bool) Ownership {
+	ownership := make(Ownership)
+	for name, module := range o[fs] {
+		if !hidden(name) {
+			ownership[name] = module
+		}
+	}
+	return ownership
+}
+
+// _reflOwners is the reflection type of owners.
+var _reflOwners = reflect.TypeFor[owners]()
+
+// recordOwner wraps the constructor fn so that the flags it registers with any
+// *flag.FlagSet or FlagSetHandle it accepts are recorded as owned by the
+// module. The wrapper takes the owners of the app as an additional first
+// parameter. Values that are not functions are returned as is.
+func recordOwner(fn any, module string) any {
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func {
+		return fn
+	}
+
+	ft := fv.Type()
+	in := []reflect.Type{_reflOwners}
+	for i := range ft.NumIn() {
+		in = append(in, ft.In(i))
+	}
+	var out []reflect.Type
+	for i := range ft.NumOut() {
+		out = append(out, ft.Out(i))
+	}
+
+	wrapper := reflect.MakeFunc(
+		reflect.FuncOf(in, out, ft.IsVariadic()),
+		func(args []reflect.Value) []reflect.Value {
+			o, params := args[0].Interface().(owners), args[1:]
+			// Record the flags registered before the call, for each flag set.
+			before := make(map[*flag.FlagSet]map[string]bool)
+			for _, arg := range params {
+				fs, ok := arg.Interface().(*flag.FlagSet)
+				if h, isHandle := arg.Interface().(FlagSetHandle); isHandle {
+					fs, ok = h.FlagSet(), true
+				}
+				if ok && fs != nil {
+					before[fs] = make(map[string]bool)
+					fs.VisitAll(func(f *flag.Flag) { before[fs][f.Name] = true })
+				}
+			}
+			defer func() {
+				for fs, known := range before {
+					fs.VisitAll(func(f *flag.Flag) {
+						if known[f.Name] {
+							return
+						}
+						if o[fs] == nil {
+							o[fs] = make(map[string]string)
+						}
+						o[fs][f.Name] = module
+					})
+				}
+			}()
+
+			if ft.IsVariadic() {
+				return fv.CallSlice(params)
+			}
+			return fv.Call(params)
+		},
+	)
+	return wrapper.Interface()
+}
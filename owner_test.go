@@ -0,0 +1,45 @@
+package flagfx_test
+
+import (
+	"flag"
+	"maps"
+	"testing"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+type verboseFlag struct{ v *bool }
+
+func newHostFlags(fs flagfx.FlagSetHandle) *hostFlag {
+	return &hostFlag{fs.String("host", "", "")}
+}
+
+func TestProvideIn(t *testing.T) {
+	var ownership flagfx.Ownership
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), []string{"-port=8080"}),
+		fx.Module("server",
+			flagfx.ProvideIn("server", func(fs *flag.FlagSet) *portFlag {
+				fs.Bool("debug-server", false, "")
+				return &portFlag{fs.Int("port", 80, "")}
+			}),
+		),
+		fx.Module("client",
+			flagfx.ProvideIn("client", newHostFlags),
+		),
+		flagfx.Provide(func(fs *flag.FlagSet) *verboseFlag { return &verboseFlag{fs.Bool("v", false, "")} }),
+		flagfx.Hidden("debug-server"),
+		fx.Invoke(func(*portFlag, *hostFlag, *verboseFlag) {}),
+		fx.Populate(&ownership),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := flagfx.Ownership{"port": "server", "host": "client"}
+	if !maps.Equal(ownership, want) {
+		t.Errorf("Ownership = %v, want %v", ownership, want)
+	}
+}
@@ -0,0 +1,72 @@
+package flagfx
+
+import (
+	"flag"
+	"io"
+)
+
+// Result holds the outcome of ParseArgs: the same values an app receives
+// once the barrier is lifted.
+type Result struct {
+	Positional  Positional
+	PassThrough PassThrough
+	Values      Values
+	Sources     Sources
+	Unknown     Unknown
+}
+
+// ParseArgs parses the arguments with the flag set like the barrier does, but
+// without an fx app, e.g. to test or fuzz how arguments are handled:
+//
+//	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+//	fs.Int("port", 8080, "port to listen on")
+//	res, err := flagfx.ParseArgs(fs, args, flagfx.Required("port"))
+//
+// The options are the ones of flagfx that take part in parsing, such as
+// Required, Validate, EnvPrefix or Preset, see Option, and run in the same
+// order and with the same precedence as in an app: the barrier of Module
+// runs the same parser core, with the Arguments, environment and other
+// dependencies of the app.
+//
+// ParseArgs is meant to be deterministic: no environment variables are
+// visible to EnvPrefix, warnings are discarded unless WarningsAsErrors is
+// used, and it never exits the process, so asking for help yields a
+// ParseError wrapping flag.ErrHelp and an informational flag such as one of
+// PrintAndExit a Result. The flag set should therefore use flag.ContinueOnError.
+func ParseArgs(fs *flag.FlagSet, args []string, opts ...Option) (Result, error) {
+	var hooks []hook
+	for _, opt := range opts {
+		hooks = append(hooks, opt.parseHooks()...)
+	}
+	p := newParser(parserParams{
+		FlagSet: fs,
+		Args:    Arguments(args),
+		Env:     func(string) (string, bool) { return "", false },
+		Warn:    warningOutput{w: io.Discard},
+		Exit:    func(int) {},
+		Context: defaultParseContext(),
+		Owners:  newOwners(),
+		Hooks:   hooks,
+	})
+	return p.run()
+}
+
+// run parses the flag set of the parser and returns the outcome. It is the
+// core shared by ParseArgs and the barrier.
+func (p *parser) run() (Result, error) {
+	if err := p.parse(); err != nil {
+		return Result{}, err
+	}
+	return p.result(), nil
+}
+
+// result returns the outcome of a completed parse.
+func (p *parser) result() Result {
+	return Result{
+		Positional:  append(Positional{}, p.fs.Args()...),
+		PassThrough: p.passThrough,
+		Values:      newValues(p.fs, p.isHidden),
+		Sources:     p.newSources(),
+		Unknown:     append(Unknown{}, p.unknown...),
+	}
+}
@@ -0,0 +1,91 @@
+package flagfx_test
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lftk/flagfx"
+)
+
+// newTestFlagSet returns a flag set that reports errors instead of exiting
+// and discards its output.
+func newTestFlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+func TestParseArgs(t *testing.T) {
+	fs := newTestFlagSet()
+	fs.Int("port", 8080, "")
+	fs.String("name", "", "")
+
+	res, err := flagfx.ParseArgs(fs, []string{"-port=9090", "a", "--", "-b"},
+		flagfx.Preset("dev", map[string]string{"name": "dev"}))
+	if err != nil {
+		t.Fatalf("ParseArgs: %v", err)
+	}
+	if want := (flagfx.Positional{"a"}); !reflect.DeepEqual(res.Positional, want) {
+		t.Errorf("Positional = %q, want %q", res.Positional, want)
+	}
+	if want := (flagfx.PassThrough{"-b"}); !reflect.DeepEqual(res.PassThrough, want) {
+		t.Errorf("PassThrough = %q, want %q", res.PassThrough, want)
+	}
+	if got := res.Values["port"]; got != "9090" {
+		t.Errorf("Values[port] = %q, want 9090", got)
+	}
+	if got := res.Sources["port"]; got != flagfx.SourceCommandLine {
+		t.Errorf("Sources[port] = %v, want %v", got, flagfx.SourceCommandLine)
+	}
+}
+
+func TestParseArgsErrors(t *testing.T) {
+	fs := newTestFlagSet()
+	fs.String("name", "", "")
+	if _, err := flagfx.ParseArgs(fs, nil, flagfx.Required("name")); err == nil {
+		t.Error("ParseArgs without a required flag succeeded")
+	}
+
+	fs = newTestFlagSet()
+	_, err := flagfx.ParseArgs(fs, []string{"-h"})
+	if !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("ParseArgs(-h) = %v, want flag.ErrHelp", err)
+	}
+}
+
+func FuzzArgs(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"-port 1 -name x a -- b",
+		"--port=1 -tag a -tag=b -v -v",
+		"-PORT=2 -na=x",
+		"-- -port=3",
+		"@missing -port",
+		"-tag=a,b -no-such-flag=1 -v=false",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		fs := newTestFlagSet()
+		fs.Int("port", 8080, "")
+		fs.String("name", "", "")
+		flagfx.DefineSlice(fs, "tag", "")
+		flagfx.DefineCount(fs, "v", "")
+		res, err := flagfx.ParseArgs(fs, strings.Fields(s),
+			flagfx.CaseInsensitive(), flagfx.AllowAbbrev(), flagfx.Interspersed(),
+			flagfx.ResponseFiles(), flagfx.MaxOccurrences("tag", 3))
+		if err != nil {
+			return
+		}
+		if res.Positional == nil || res.PassThrough == nil || res.Unknown == nil {
+			t.Fatalf("ParseArgs(%q) returned nil slices: %+v", s, res)
+		}
+		if _, ok := res.Values["port"]; !ok {
+			t.Fatalf("ParseArgs(%q) lost -port from Values", s)
+		}
+	})
+}
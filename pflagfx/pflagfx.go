@@ -0,0 +1,91 @@
+// Package pflagfx mirrors flagfx for github.com/spf13/pflag, providing
+// POSIX/GNU-style flags such as --log-level and -l to fx applications.
+// Constructors accept a *pflag.FlagSet instead of a *flag.FlagSet.
+//
+// Only the core of flagfx is mirrored: Module, FlagSet, Args, Provide and
+// Positional. A parse failure is reported as a flagfx.ParseError, so
+// flagfx.Run exits with code 2. The options of flagfx, such as ErrorHandling,
+// EnvPrefix, ConfigFile or Required, hook into the flagfx barrier and have no
+// effect on a pflagfx app; the error handling is chosen with pflag.NewFlagSet
+// instead.
+//
+// The pflag dependency is isolated in this package: as a requirement of the
+// flagfx module it is listed in the go.mod of apps using flagfx, but only apps
+// importing pflagfx compile and link it. Both packages rely on an fx barrier,
+// of which an app can only have one, so an app uses either flagfx or pflagfx.
+package pflagfx
+
+import (
+	"os"
+
+	"github.com/spf13/pflag"
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+	"github.com/lftk/fxbarrier"
+)
+
+// Module is the core `fx.Module` for the pflagfx system.
+var Module = fx.Module("pflagfx",
+	// Provide the default dependencies for the parse action.
+	fx.Provide(defaultFlagSet, defaultArgs),
+	// The barrier ensures that flags are parsed before any constructors provided
+	// via this module's Provide function are invoked.
+	fxbarrier.Barrier("pflagfx",
+		func(fs *pflag.FlagSet, args Arguments) error {
+			if err := fs.Parse(args); err != nil {
+				return flagfx.ParseError{Args: flagfx.Arguments(args), Err: err}
+			}
+			return nil
+		},
+	),
+	// The parsed flag set is only handed out once the barrier is lifted.
+	fxbarrier.Provide("pflagfx", func(fs *pflag.FlagSet) parsed {
+		return parsed{fs: fs}
+	}),
+	fx.Provide(func(p parsed) Positional {
+		return append(Positional{}, p.fs.Args()...)
+	}),
+)
+
+// defaultFlagSet provides the default flag set, which is the global pflag.CommandLine.
+// This can be replaced using the FlagSet option.
+func defaultFlagSet() *pflag.FlagSet {
+	return pflag.CommandLine
+}
+
+// defaultArgs provides the default command-line arguments, which are os.Args[1:].
+// This can be replaced using the Args option.
+func defaultArgs() Arguments {
+	return os.Args[1:]
+}
+
+// FlagSet allows replacing the default `*pflag.FlagSet` (which is pflag.CommandLine)
+// with a custom one.
+func FlagSet(fs *pflag.FlagSet) fx.Option {
+	return fx.Replace(fs)
+}
+
+// Arguments represents the command-line Arguments to be parsed.
+type Arguments []string
+
+// Args allows replacing the default command-line arguments (os.Args[1:])
+// with a custom slice of strings.
+func Args(args []string) fx.Option {
+	return fx.Replace(Arguments(args))
+}
+
+// Positional holds the non-flag arguments remaining after parsing.
+// It is never nil, and must be consumed via fx.Provide or fx.Invoke.
+type Positional []string
+
+// parsed gives access to the flag set once it has been parsed.
+type parsed struct {
+	fs *pflag.FlagSet
+}
+
+// Provide is a wrapper around fxbarrier.Provide for use with pflag flags.
+// It uses the "pflagfx" barrier to ensure flags are parsed before dependents are instantiated.
+func Provide(constructors ...any) fx.Option {
+	return fxbarrier.Provide("pflagfx", constructors...)
+}
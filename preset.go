@@ -0,0 +1,94 @@
+package flagfx
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Preset returns an fx.Option defining a preset of flag values, selected on
+// the command line with -preset=name. The preset applies its values to the
+// flags that were not set on the command line, which therefore always takes
+// precedence:
+//
+//	command line > preset > environment > config file > flag default
+//
+// The option can be used several times to define several presets, which are
+// listed in the usage of -preset. Defining a preset twice, or a preset naming
+// a flag that is not defined in the flag set, is a configuration error.
+// Selecting two different presets on the command line fails the app with a
+// ValidationError naming both, while repeating the same one is allowed.
+func Preset(name string, values map[string]string) Option {
+	values = maps.Clone(values)
+	return addHook(stageSetup, func(p *parser) error {
+		if _, ok := p.presets[name]; ok {
+			return fmt.Errorf("flagfx: preset %q is already defined", name)
+		}
+		if p.presets == nil {
+			p.presets = make(map[string]map[string]string)
+			optionVar(p.fs, "preset", "", presetValue{p: p})
+		}
+		p.presets[name] = values
+		names := slices.Sorted(maps.Keys(p.presets))
+		p.fs.Lookup("preset").Usage = fmt.Sprintf("apply a preset of flag values (one of %s)", strings.Join(names, ", "))
+		return nil
+	})
+}
+
+// applyPreset checks the presets and applies the values of the selected one
+// to the flags that are still unset.
+func (p *parser) applyPreset() error {
+	for _, name := range slices.Sorted(maps.Keys(p.presets)) {
+		for _, key := range slices.Sorted(maps.Keys(p.presets[name])) {
+			if p.fs.Lookup(key) == nil {
+				return fmt.Errorf("flagfx: preset %q: flag -%s is not defined", name, key)
+			}
+		}
+	}
+	if p.presetConflict != "" {
+		return validationErrorf("flagfx: presets %q and %q are both selected, only one may be given", p.preset, p.presetConflict)
+	}
+	if p.preset == "" {
+		return nil
+	}
+	values := p.presets[p.preset]
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		if p.set[name] {
+			continue
+		}
+		if err := p.setFallback(name, values[name], SourcePreset); err != nil {
+			return fmt.Errorf("flagfx: preset %q: invalid value %q for flag -%s: %v", p.preset, values[name], name, err)
+		}
+	}
+	return nil
+}
+
+// presetValue is the flag.Value of -preset, selecting one of the presets.
+type presetValue struct {
+	p *parser
+}
+
+// String returns the selected preset.
+func (v *presetValue) String() string {
+	if v == nil || v.p == nil {
+		return ""
+	}
+	return v.p.preset
+}
+
+// Set selects the preset.
+func (v *presetValue) Set(value string) error {
+	if _, ok := v.p.presets[value]; !ok {
+		return fmt.Errorf("must be one of %s", strings.Join(slices.Sorted(maps.Keys(v.p.presets)), ", "))
+	}
+	if v.p.preset != "" && v.p.preset != value {
+		// Report the conflict once all flags are parsed, as a ValidationError.
+		if v.p.presetConflict == "" {
+			v.p.presetConflict = value
+		}
+		return nil
+	}
+	v.p.preset = value
+	return nil
+}
@@ -0,0 +1,48 @@
+package flagfx_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestPreset(t *testing.T) {
+	tests := []struct {
+		args  []string
+		level string
+		err   string
+	}{
+		{nil, "info", ""},
+		{[]string{"-preset=dev"}, "debug", ""},
+		{[]string{"-preset=dev", "-log-level=warn"}, "warn", ""},
+		{[]string{"-preset=dev", "-preset=dev"}, "debug", ""},
+		{[]string{"-preset=dev", "-preset=prod"}, "", `flagfx: presets "dev" and "prod" are both selected, only one may be given`},
+		{[]string{"-preset=prod", "-preset=dev", "-preset=prod"}, "", `flagfx: presets "prod" and "dev" are both selected`},
+		{[]string{"-preset=test"}, "", "must be one of dev, prod"},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		level := fs.String("log-level", "info", "")
+		_, err := flagfx.ParseArgs(fs, tt.args,
+			flagfx.Preset("dev", map[string]string{"log-level": "debug"}),
+			flagfx.Preset("prod", map[string]string{"log-level": "error"}),
+		)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: error %v, want %q", tt.args, err, tt.err)
+			}
+			var verr flagfx.ValidationError
+			if strings.HasPrefix(tt.err, "flagfx: presets") && !errors.As(err, &verr) {
+				t.Errorf("%q: error %v is not a ValidationError", tt.args, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+		} else if *level != tt.level {
+			t.Errorf("%q: -log-level = %s, want %s", tt.args, *level, tt.level)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package flagfx
+
+import (
+	"flag"
+	"runtime"
+	"sync"
+	"weak"
+)
+
+// snapshots maps a weak pointer to a flag set to the *snapshot of its defaults.
+// Entries are removed once the flag set is garbage collected.
+var snapshots sync.Map
+
+// snapshot holds the functions restoring the defaults of a flag set's flags.
+type snapshot struct {
+	mu     sync.Mutex
+	resets map[string]func()
+}
+
+// recordDefault records how to restore the default of the named flag.
+// It is called by the Define helpers and Bind when registering a flag.
+func recordDefault(fs *flag.FlagSet, name string, reset func()) {
+	key := weak.Make(fs)
+	v, loaded := snapshots.LoadOrStore(key, &snapshot{resets: make(map[string]func())})
+	if !loaded {
+		runtime.AddCleanup(fs, func(key weak.Pointer[flag.FlagSet]) {
+			snapshots.Delete(key)
+		}, key)
+	}
+	s := v.(*snapshot)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resets[name] = reset
+}
+
+// moveDefault moves the recorded default of a flag registered on one flag set
+// to a flag registered on another, e.g. under a prefixed name.
+func moveDefault(from *flag.FlagSet, fromName string, to *flag.FlagSet, toName string) {
+	v, ok := snapshots.Load(weak.Make(from))
+	if !ok {
+		return
+	}
+	s := v.(*snapshot)
+	s.mu.Lock()
+	reset, ok := s.resets[fromName]
+	delete(s.resets, fromName)
+	s.mu.Unlock()
+	if ok {
+		recordDefault(to, toName, reset)
+	}
+}
+
+// chainDefault extends the recorded default of the named flag with seed, which
+// is called after the original reset, e.g. to restore a default overridden by
+// Defaults. Flags without a recorded default are left alone.
+func chainDefault(fs *flag.FlagSet, name string, seed func()) {
+	v, ok := snapshots.Load(weak.Make(fs))
+	if !ok {
+		return
+	}
+	s := v.(*snapshot)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if reset, ok := s.resets[name]; ok {
+		s.resets[name] = func() {
+			reset()
+			seed()
+		}
+	}
+}
+
+// Reparse resets the flags of the flag set to their defaults and parses args
+// again, e.g. to handle each line of a REPL with the same registered flags.
+// A parse failure is returned as a ParseError.
+//
+// The flag package offers no way to reset a flag, so only flags registered via
+// the Define helpers or Bind, which snapshot their defaults, are reset. Flags
+// registered directly, e.g. with fs.StringVar, keep their previous values.
+// Note that fs.Visit keeps reporting flags set by previous parses.
+func Reparse(fs *flag.FlagSet, args []string) error {
+	if v, ok := snapshots.Load(weak.Make(fs)); ok {
+		s := v.(*snapshot)
+		s.mu.Lock()
+		for _, reset := range s.resets {
+			reset()
+		}
+		s.mu.Unlock()
+	}
+	if err := fs.Parse(args); err != nil {
+		return ParseError{Args: args, Err: err}
+	}
+	return nil
+}
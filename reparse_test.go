@@ -0,0 +1,63 @@
+package flagfx_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestReparse(t *testing.T) {
+	type options struct {
+		Name  string `flag:"name" default:"anon"`
+		Quiet bool   `flag:"quiet"`
+	}
+	fs := newTestFlagSet()
+	port := flagfx.Define(fs, "port", 80, "")
+	tags := flagfx.DefineSlice(fs, "tag", "")
+	labels := flagfx.DefineMap(fs, "label", "")
+	verbosity := flagfx.DefineCount(fs, "v", "")
+	opts := flagfx.Bind[options](fs, "")
+	host := fs.String("host", "localhost", "") // Not reset.
+
+	state := func() string {
+		return fmt.Sprintf("port=%d tag=%q label=%v v=%d name=%s quiet=%t host=%s",
+			*port, *tags, *labels, *verbosity, opts.Name, opts.Quiet, *host)
+	}
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"-port=1", "-tag=a", "-tag=b", "-label=k=v", "-v", "-v", "-name=jane", "-quiet", "-host=example.com"},
+			`port=1 tag=["a" "b"] label=map[k:v] v=2 name=jane quiet=true host=example.com`},
+		{nil, `port=80 tag=[] label=map[] v=0 name=anon quiet=false host=example.com`},
+		{[]string{"-tag=c", "-v"}, `port=80 tag=["c"] label=map[] v=1 name=anon quiet=false host=example.com`},
+		{[]string{"-host=other"}, `port=80 tag=[] label=map[] v=0 name=anon quiet=false host=other`},
+	}
+	for _, tt := range tests {
+		if err := flagfx.Reparse(fs, tt.args); err != nil {
+			t.Fatalf("%q: %v", tt.args, err)
+		}
+		if got := state(); got != tt.want {
+			t.Errorf("%q:\ngot  %s\nwant %s", tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestReparseError(t *testing.T) {
+	fs := newTestFlagSet()
+	port := flagfx.Define(fs, "port", 80, "")
+	if err := flagfx.Reparse(fs, []string{"-port=1"}); err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"-missing"}
+	err := flagfx.Reparse(fs, args)
+	var perr flagfx.ParseError
+	if !errors.As(err, &perr) || !slicesEqual(perr.Args, args) {
+		t.Fatalf("got %v, want a ParseError for %q", err, args)
+	}
+	if *port != 80 {
+		t.Errorf("-port = %d, want the default restored before the failed parse", *port)
+	}
+}
@@ -0,0 +1,197 @@
+package flagfx
+
+import (
+	"flag"
+
+	"go.uber.org/fx"
+)
+
+// Positional holds the non-flag arguments remaining after parsing.
+// It is never nil; it is empty when there are no positional arguments.
+//
+// Positional is only available once flags have been parsed, so it must be
+// consumed via fx.Provide or fx.Invoke rather than by constructors passed to
+// Provide, which run before parsing.
+type Positional []string
+
+// PassThrough holds the arguments following the first standalone "--" in
+// Arguments, verbatim. They are never parsed as flags, which makes them
+// suitable for proxying to a subprocess. Only the arguments before "--" are
+// parsed, so PassThrough is not part of Positional. It is never nil.
+//
+// Like Positional, PassThrough must be consumed via fx.Provide or fx.Invoke.
+type PassThrough []string
+
+// Parsed reports that the flag set has been parsed, either by the barrier
+// or, when the barrier was skipped, by code outside flagfx. It is always true
+// once injected, so depending on it orders a constructor after parsing.
+//
+// Like Positional, Parsed must be consumed via fx.Provide or fx.Invoke.
+type Parsed bool
+
+// ParsedFlagSet is the flag set parsed by flagfx, the default one or the one
+// supplied via FlagSet. It embeds the *flag.FlagSet, whose methods are
+// therefore available, but unlike *flag.FlagSet, which fx hands out before
+// parsing to the constructors passed to Provide, it only resolves once the
+// barrier is lifted, so Parsed always reports true.
+//
+// Like Positional, ParsedFlagSet must be consumed via fx.Provide or fx.Invoke.
+// A constructor passed to Provide cannot depend on it, as it runs before
+// parsing to register its flags.
+type ParsedFlagSet struct {
+	*flag.FlagSet
+}
+
+// Ready signals that parsing has completed. It carries no data; depending on
+// it orders a constructor or invoke after the barrier, e.g. one that appends
+// an fx.Lifecycle OnStart hook reading flag values:
+//
+//	fx.Invoke(func(_ flagfx.Ready, lc fx.Lifecycle, f *flags) {
+//		lc.Append(fx.StartHook(func() { ... }))
+//	})
+//
+// Constructors passed to Provide also run inside the barrier, but before
+// parsing, to register their flags; only their results wait for the barrier.
+// Ready suits code that registers no flags but must observe the parsed ones.
+// Since the barrier only runs when something depends on its results,
+// depending on Ready also ensures that the flags are parsed at all.
+//
+// Like Positional, Ready must be consumed via fx.Provide or fx.Invoke.
+type Ready struct{}
+
+// Values maps the name of every flag in the flag set to the string form of
+// its effective value after parsing, including values applied from fallbacks
+// such as EnvPrefix. It is a copy, so modifying it does not affect the flag set.
+// Flags passed to Hidden are left out, unless ShowHidden is used.
+//
+// Like Positional, Values must be consumed via fx.Provide or fx.Invoke.
+type Values map[string]string
+
+// newValues records the current value of every flag in the flag set, except
+// for the flags for which hidden reports true.
+func newValues(fs *flag.FlagSet, hidden func(name string) bool) Values {
+	values := make(Values)
+	fs.VisitAll(func(f *flag.Flag) {
+		if hidden(f.Name) {
+			return
+		}
+		values[f.Name] = f.Value.String()
+	})
+	return values
+}
+
+// Lookup answers questions about the parsed flag set, such as whether a flag
+// was explicitly set, which allows three-state logic for boolean flags
+// (set to true, set to false, or left at its default).
+//
+// Like Positional, Lookup must be consumed via fx.Provide or fx.Invoke.
+type Lookup struct {
+	fs  *flag.FlagSet
+	set map[string]bool
+}
+
+// Set reports whether the named flag was explicitly set, on the command line
+// or by a fallback such as EnvPrefix, rather than left at its default.
+func (l Lookup) Set(name string) bool {
+	return l.set[name]
+}
+
+// Explicit returns the string form of the value of the named flag if it was
+// explicitly set, like os.LookupEnv does for environment variables. It tells
+// -name= apart from an unset flag: the first yields "", true and the second
+// "", false, whatever the default of the flag.
+func (l Lookup) Explicit(name string) (string, bool) {
+	if !l.set[name] {
+		return "", false
+	}
+	return l.fs.Lookup(name).Value.String(), true
+}
+
+// Get returns the value of the named flag, reporting whether the flag exists.
+func (l Lookup) Get(name string) (flag.Value, bool) {
+	f := l.fs.Lookup(name)
+	if f == nil {
+		return nil, false
+	}
+	return f.Value, true
+}
+
+// Flags gives access to the flags of the parsed flag set with their metadata,
+// such as the usage and the default, e.g. to render a configuration editor.
+// Unlike Values, it returns the live *flag.Flag of the flag set, so changing
+// a flag, e.g. calling Value.Set, affects the flag set and everyone reading
+// from it. Flags passed to Hidden are left out, unless ShowHidden is used.
+//
+// Like Positional, Flags must be consumed via fx.Provide or fx.Invoke.
+type Flags struct {
+	fs     *flag.FlagSet
+	hidden func(name string) bool
+}
+
+// Get returns the named flag, reporting whether it exists.
+func (f Flags) Get(name string) (*flag.Flag, bool) {
+	fl := f.fs.Lookup(name)
+	if fl == nil || f.hidden(name) {
+		return nil, false
+	}
+	return fl, true
+}
+
+// All returns the flags in lexicographical order.
+func (f Flags) All() []*flag.Flag {
+	var flags []*flag.Flag
+	f.fs.VisitAll(func(fl *flag.Flag) {
+		if !f.hidden(fl.Name) {
+			flags = append(flags, fl)
+		}
+	})
+	return flags
+}
+
+// result gives access to the parser once the barrier has been lifted.
+type result struct {
+	p *parser
+}
+
+// newResult is provided through the barrier, so a result only
+// becomes available after parsing has completed.
+func newResult(p *parser) result {
+	return result{p: p}
+}
+
+// resultOut holds the values derived from a completed parse.
+type resultOut struct {
+	fx.Out
+
+	Positional  Positional
+	PassThrough PassThrough
+	Values      Values
+	Lookup      Lookup
+	Parsed      Parsed
+	Command     Command
+	FlagSet     ParsedFlagSet
+	Sources     Sources
+	Ready       Ready
+	Flags       Flags
+	Ownership   Ownership
+	Unknown     Unknown
+}
+
+// provideResult derives the injectable values from a completed parse.
+func provideResult(r result) resultOut {
+	res := r.p.result()
+	return resultOut{
+		Positional:  res.Positional,
+		PassThrough: res.PassThrough,
+		Values:      res.Values,
+		Lookup:      Lookup{fs: r.p.fs, set: setFlags(r.p.fs)},
+		Parsed:      Parsed(r.p.fs.Parsed()),
+		Command:     r.p.commandName(),
+		FlagSet:     ParsedFlagSet{FlagSet: r.p.fs},
+		Sources:     res.Sources,
+		Ready:       Ready{},
+		Flags:       Flags{fs: r.p.fs, hidden: r.p.isHidden},
+		Ownership:   r.p.owners.ownership(r.p.fs, r.p.isHidden),
+		Unknown:     res.Unknown,
+	}
+}
@@ -0,0 +1,77 @@
+package flagfx_test
+
+import (
+	"flag"
+	"testing"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestPassThrough(t *testing.T) {
+	tests := []struct {
+		args        []string
+		positional  []string
+		passThrough []string
+	}{
+		{[]string{"-v", "a", "b"}, []string{"a", "b"}, nil},
+		{[]string{"-v", "a", "--"}, []string{"a"}, nil},
+		{[]string{"-v", "--", "-x", "--y=1", "--", "z"}, nil, []string{"-x", "--y=1", "--", "z"}},
+		{[]string{"a", "--", "b"}, []string{"a"}, []string{"b"}},
+	}
+	for _, tt := range tests {
+		var positional flagfx.Positional
+		var passThrough flagfx.PassThrough
+		fs := newTestFlagSet()
+		v := fs.Bool("v", false, "")
+		app := fx.New(
+			fx.NopLogger,
+			flagfx.ModuleFor(fs, tt.args),
+			fx.Populate(&positional, &passThrough),
+		)
+		if err := app.Err(); err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if passThrough == nil {
+			t.Errorf("%q: PassThrough is nil", tt.args)
+		}
+		if !slicesEqual(positional, tt.positional) || !slicesEqual(passThrough, tt.passThrough) {
+			t.Errorf("%q: Positional = %q, PassThrough = %q, want %q and %q",
+				tt.args, positional, passThrough, tt.positional, tt.passThrough)
+		}
+		if tt.args[0] == "-v" && !*v {
+			t.Errorf("%q: -v not set", tt.args)
+		}
+	}
+}
+
+func TestParsedFlagSet(t *testing.T) {
+	type consumer struct{ port string }
+	var c *consumer
+	fs := newTestFlagSet()
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(fs, []string{"-port=8080"}),
+		flagfx.Provide(func(fs *flag.FlagSet) *portFlag { return &portFlag{fs.Int("port", 80, "")} }),
+		// A plain constructor, not gated by the barrier, receiving the
+		// flag set only once it is parsed.
+		fx.Provide(func(pfs flagfx.ParsedFlagSet) *consumer {
+			if !pfs.Parsed() {
+				t.Error("ParsedFlagSet handed out before parsing")
+			}
+			if pfs.FlagSet != fs {
+				t.Error("ParsedFlagSet is not the flag set of the app")
+			}
+			return &consumer{port: pfs.Lookup("port").Value.String()}
+		}),
+		fx.Populate(&c),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if c.port != "8080" {
+		t.Errorf("-port = %s, want 8080", c.port)
+	}
+}
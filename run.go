@@ -0,0 +1,77 @@
+package flagfx
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"go.uber.org/fx"
+)
+
+// Exit codes returned by Run.
+const (
+	exitOK    = 0 // The app ran successfully, or help was requested.
+	exitError = 1 // The app failed to build, start or stop.
+	exitUsage = 2 // The arguments failed to parse or validate, like the flag package uses.
+)
+
+// Run builds the app from the options, which must include Module or
+// ModuleFor, starts it and waits for it to be shut down, like fx.App.Run.
+// It returns the exit code for the caller to pass to os.Exit:
+//
+//   - 0 when the app ran successfully, or help was requested with -h using
+//     an Exiter that returns, see HelpExitCode;
+//   - 2 when the arguments failed to parse, see ParseError, or failed a
+//     check such as Required or NoPositional, see ValidationError;
+//   - 1 when the app failed otherwise, e.g. a constructor returned an error;
+//   - the code given to fx.Shutdowner with fx.ExitCode, if any.
+//
+// Errors are written to os.Stderr, except for parse errors, which the flag
+// package has already reported along with the usage.
+//
+//	func main() {
+//		os.Exit(flagfx.Run(flagfx.Module, ...))
+//	}
+func Run(opts ...fx.Option) int {
+	app := fx.New(opts...)
+	if err := app.Err(); err != nil {
+		return exitCode(err)
+	}
+
+	startCtx, cancel := context.WithTimeout(context.Background(), app.StartTimeout())
+	defer cancel()
+	if err := app.Start(startCtx); err != nil {
+		return exitCode(err)
+	}
+
+	sig := <-app.Wait()
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), app.StopTimeout())
+	defer cancel()
+	if err := app.Stop(stopCtx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
+	}
+	return sig.ExitCode
+}
+
+// exitCode maps the error of an app to its exit code, reporting the error if
+// the flag package has not already done so.
+func exitCode(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	var perr ParseError
+	if errors.As(err, &perr) {
+		return exitUsage
+	}
+	var verr ValidationError
+	if errors.As(err, &verr) {
+		fmt.Fprintln(os.Stderr, err)
+		return exitUsage
+	}
+	fmt.Fprintln(os.Stderr, err)
+	return exitError
+}
@@ -0,0 +1,110 @@
+package flagfx_test
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestRunExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		opt  fx.Option
+		want int
+	}{
+		{"parse error", []string{"-bad"}, fx.Options(), 2},
+		{"help", []string{"-h"}, fx.Options(), 0},
+		{"required", nil, flagfx.Required("port"), 2},
+		{"mutually exclusive", []string{"-port=1", "-host=x"}, flagfx.MutuallyExclusive("port", "host"), 2},
+		{"no positional", []string{"a"}, flagfx.NoPositional(), 2},
+		{"validate", []string{"-port=0"}, flagfx.Validate(map[string]func(string) error{
+			"port": func(string) error { return errors.New("must be positive") },
+		}), 2},
+		{"ambiguous abbreviation", []string{"-ver"}, flagfx.AllowAbbrev(), 2},
+		{"undefined required flag", nil, flagfx.Required("missing"), 1},
+		{"constructor", nil, fx.Invoke(func(flagfx.Ready) error { return errors.New("boom") }), 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := newTestFlagSet()
+			fs.Int("port", 80, "")
+			fs.String("host", "", "")
+			fs.Bool("verbose", false, "")
+			fs.Bool("version", false, "")
+			got := flagfx.Run(
+				fx.NopLogger,
+				flagfx.ModuleFor(fs, tt.args),
+				fx.Replace(flagfx.Exiter(func(int) {})),
+				fx.Invoke(func(flagfx.Ready) {}),
+				tt.opt,
+			)
+			if got != tt.want {
+				t.Errorf("Run() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	fs := newTestFlagSet()
+	fs.Int("port", 80, "")
+	_, err := flagfx.ParseArgs(fs, nil, flagfx.Required("port"))
+	var verr flagfx.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("error %v is not a ValidationError", err)
+	}
+	if got, want := err.Error(), "flagfx: missing required flags: -port"; got != want {
+		t.Errorf("error = %q, want %q", got, want)
+	}
+}
+
+// captureStderr returns what fn writes to os.Stderr.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+	out := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- b
+	}()
+	fn()
+	w.Close()
+	return string(<-out)
+}
+
+func TestRunSuggestUnknown(t *testing.T) {
+	var code int
+	stderr := captureStderr(t, func() {
+		// The flag set writes to os.Stderr, like flag.CommandLine does.
+		fs := flag.NewFlagSet("app", flag.ContinueOnError)
+		fs.String("log-level", "info", "log level")
+		code = flagfx.Run(
+			fx.NopLogger,
+			flagfx.ModuleFor(fs, []string{"-loglvl=debug"}),
+			flagfx.SuggestUnknown(),
+			fx.Invoke(func(flagfx.Ready) {}),
+		)
+	})
+	if code != 2 {
+		t.Errorf("Run() = %d, want 2", code)
+	}
+	for _, want := range []string{"flag provided but not defined: -loglvl\n", "did you mean -log-level?\n"} {
+		if strings.Count(stderr, want) != 1 {
+			t.Errorf("stderr %q does not report %q once", stderr, want)
+		}
+	}
+}
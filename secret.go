@@ -0,0 +1,125 @@
+package flagfx
+
+import (
+	"flag"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// DefineSecret registers a string flag for a sensitive value with the flag set
+// and returns a pointer to its value. Besides a literal, the flag accepts "-"
+// to read the value from standard input, or @path or file:path to read it from
+// a file, such as a secret mounted into a container, so that the secret does
+// not have to appear on the command line. Trailing newlines are trimmed from
+// the contents read.
+//
+// Literal values that would otherwise be read from somewhere are escaped by
+// doubling: @@value stands for @value, file::value for file:value, and "--"
+// for "-". Only the first "@", the colon or one dash is removed, so @@@value
+// stands for @@value and "---" for "--".
+//
+// With ResponseFiles, an argument of the form @path is expanded as a response
+// file before the flags are parsed, including the value of a secret flag
+// given as a separate argument, as in -token @path. Attach the value with "="
+// instead, as in -token=@path or -token=@@value, or use file:path, which
+// response files leave alone.
+//
+// The value of a secret flag renders as **** wherever it is shown, such as in
+// Values, Dump and the usage output, while the returned pointer holds the
+// resolved secret.
+func DefineSecret(fs *flag.FlagSet, name, usage string) *string {
+	var s string
+	fs.Var(&secretValue{value: &s}, name, usage)
+	recordDefault(fs, name, func() { s = "" })
+	return &s
+}
+
+// SecretInput returns an fx.Option that replaces standard input as the source
+// of secret flags given the value "-", for example in tests.
+func SecretInput(r io.Reader) Option {
+	return addHook(stageSetup, func(p *parser) error {
+		p.fs.VisitAll(func(f *flag.Flag) {
+			if v, ok := f.Value.(*secretValue); ok {
+				v.stdin = r
+			}
+		})
+		return nil
+	})
+}
+
+// SecretFS returns an fx.Option that replaces the file system as the source of
+// secret flags given @path or file:path, for example in tests. Paths are
+// resolved relative to the root of fsys, with any leading slash removed.
+func SecretFS(fsys fs.FS) Option {
+	return addHook(stageSetup, func(p *parser) error {
+		p.fs.VisitAll(func(f *flag.Flag) {
+			if v, ok := f.Value.(*secretValue); ok {
+				v.fsys = fsys
+			}
+		})
+		return nil
+	})
+}
+
+// secretValue is a flag.Value for a sensitive string.
+type secretValue struct {
+	value *string
+	stdin io.Reader // Defaults to os.Stdin.
+	fsys  fs.FS     // Defaults to the file system of the OS.
+}
+
+// String masks the value.
+func (v *secretValue) String() string {
+	if v == nil || v.value == nil || *v.value == "" {
+		return ""
+	}
+	return "****"
+}
+
+// Set resolves the value, reading it from standard input for "-" and from
+// the file for @path and file:path, and unescaping the literals.
+func (v *secretValue) Set(value string) error {
+	var (
+		data []byte
+		err  error
+	)
+	switch {
+	case value == "-":
+		stdin := v.stdin
+		if stdin == nil {
+			stdin = os.Stdin
+		}
+		data, err = io.ReadAll(stdin)
+	case strings.Trim(value, "-") == "" && len(value) > 1:
+		*v.value = value[1:]
+		return nil
+	case strings.HasPrefix(value, "@@"):
+		*v.value = value[1:]
+		return nil
+	case strings.HasPrefix(value, "@"):
+		data, err = v.readFile(value[1:])
+	case strings.HasPrefix(value, "file::"):
+		*v.value = "file:" + value[len("file::"):]
+		return nil
+	case strings.HasPrefix(value, "file:"):
+		data, err = v.readFile(value[len("file:"):])
+	default:
+		*v.value = value
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	*v.value = strings.TrimRight(string(data), "\r\n")
+	return nil
+}
+
+// readFile reads the named file from the file system of the value.
+func (v *secretValue) readFile(name string) ([]byte, error) {
+	if v.fsys == nil {
+		return os.ReadFile(name)
+	}
+	return fs.ReadFile(v.fsys, strings.TrimPrefix(name, "/"))
+}
@@ -0,0 +1,103 @@
+package flagfx_test
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestDefineSecret(t *testing.T) {
+	fsys := fstest.MapFS{
+		"run/secrets/token": {Data: []byte("from-file\n")},
+	}
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"-token=literal", "literal"},
+		{"-token=-", "from-stdin"},
+		{"-token=@/run/secrets/token", "from-file"},
+		{"-token=@@literal", "@literal"},
+		{"-token=@@@literal", "@@literal"},
+		{"-token=--", "-"},
+		{"-token=---", "--"},
+		{"-token=-x", "-x"},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		token := flagfx.DefineSecret(fs, "token", "API token")
+		res, err := flagfx.ParseArgs(fs, []string{tt.arg},
+			flagfx.SecretInput(strings.NewReader("from-stdin\r\n")),
+			flagfx.SecretFS(fsys),
+		)
+		if err != nil {
+			t.Errorf("%s: %v", tt.arg, err)
+			continue
+		}
+		if *token != tt.want {
+			t.Errorf("%s: token = %q, want %q", tt.arg, *token, tt.want)
+		}
+		if got := res.Values["token"]; got != "****" {
+			t.Errorf("%s: Values[token] = %q, want it masked", tt.arg, got)
+		}
+	}
+}
+
+func TestDefineSecretErrors(t *testing.T) {
+	fs := newTestFlagSet()
+	flagfx.DefineSecret(fs, "token", "API token")
+	if _, err := flagfx.ParseArgs(fs, []string{"-token=@missing"}, flagfx.SecretFS(fstest.MapFS{})); err == nil {
+		t.Error("-token=@missing: no error")
+	}
+
+	fs = newTestFlagSet()
+	flagfx.DefineSecret(fs, "token", "API token")
+	res, err := flagfx.ParseArgs(fs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Values["token"]; got != "" {
+		t.Errorf("unset Values[token] = %q, want empty", got)
+	}
+}
+
+func TestDefineSecretFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"run/secrets/token": {Data: []byte("from-file\n\n")},
+		"token":             {Data: []byte("relative")},
+	}
+	tests := []struct {
+		arg  string
+		want string
+	}{
+		{"-token=from-literal", "from-literal"},
+		{"-token=file:/run/secrets/token", "from-file"},
+		{"-token=file:token", "relative"},
+		{"-token=file::literal", "file:literal"},
+		{"-token=file:::literal", "file::literal"},
+		{"-token=file", "file"},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		token := flagfx.DefineSecret(fs, "token", "API token")
+		res, err := flagfx.ParseArgs(fs, []string{tt.arg}, flagfx.SecretFS(fsys))
+		if err != nil {
+			t.Errorf("%s: %v", tt.arg, err)
+			continue
+		}
+		if *token != tt.want {
+			t.Errorf("%s: token = %q, want %q", tt.arg, *token, tt.want)
+		}
+		if got := res.Values["token"]; got != "****" {
+			t.Errorf("%s: Values[token] = %q, want it masked", tt.arg, got)
+		}
+	}
+
+	fs := newTestFlagSet()
+	flagfx.DefineSecret(fs, "token", "API token")
+	if _, err := flagfx.ParseArgs(fs, []string{"-token=file:missing"}, flagfx.SecretFS(fsys)); err == nil {
+		t.Error("-token=file:missing: no error")
+	}
+}
@@ -0,0 +1,105 @@
+package flagfx
+
+import (
+	"reflect"
+
+	"go.uber.org/fx"
+)
+
+// Snapshot returns an fx.Option that provides a copy of the *T provided via
+// Provide, by value. The copy is taken once the barrier is lifted, so
+// consumers of T only ever observe the parsed values:
+//
+//	flagfx.Provide(newFlags), // Returns *flags.
+//	flagfx.Snapshot[flags](),
+//	fx.Invoke(func(f flags) { ... }),
+//
+// The *T is shared with the flag set, which writes through it whenever a flag
+// is set, so reading it concurrently with code that still sets flags, e.g. via
+// the *flag.FlagSet, is a data race. The snapshot is a deep copy: slices, maps
+// and pointers are copied as well, so it is never written to by flagfx and
+// can be read from any goroutine, and consumers can't change the flag values
+// through it. Cycles are preserved, and memory shared within T, such as two
+// fields pointing to the same value, is shared within the copy. Channels,
+// functions and interfaces are copied as is, and so are unexported fields,
+// which reflection cannot copy deeply: slices or maps held by them remain
+// shared.
+func Snapshot[T any]() fx.Option {
+	return fx.Provide(func(p *T) T {
+		return deepCopy(reflect.ValueOf(p).Elem()).Interface().(T)
+	})
+}
+
+// deepCopy returns a copy of v that shares no memory reachable through
+// slices, maps, pointers or exported fields with it.
+func deepCopy(v reflect.Value) reflect.Value {
+	return make(copier).copy(v)
+}
+
+// copier makes a deep copy. It copies each pointer, map and slice it reaches
+// once, so that copying a cyclic value terminates, and memory shared within
+// the value is shared within the copy as well.
+type copier map[copyKey]reflect.Value
+
+// copyKey identifies a pointer, map or slice that has been copied.
+type copyKey struct {
+	ptr uintptr
+	typ reflect.Type
+	len int // The length of a slice.
+}
+
+// copy returns a copy of v.
+func (cp copier) copy(v reflect.Value) reflect.Value {
+	c := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Pointer:
+		if !v.IsNil() {
+			key := copyKey{ptr: v.Pointer(), typ: v.Type()}
+			if p, ok := cp[key]; ok {
+				return p
+			}
+			p := reflect.New(v.Type().Elem())
+			cp[key] = p
+			p.Elem().Set(cp.copy(v.Elem()))
+			c.Set(p)
+		}
+	case reflect.Slice:
+		if !v.IsNil() {
+			key := copyKey{ptr: v.Pointer(), typ: v.Type(), len: v.Len()}
+			if s, ok := cp[key]; ok {
+				return s
+			}
+			c.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+			cp[key] = c
+			for i := range v.Len() {
+				c.Index(i).Set(cp.copy(v.Index(i)))
+			}
+		}
+	case reflect.Array:
+		for i := range v.Len() {
+			c.Index(i).Set(cp.copy(v.Index(i)))
+		}
+	case reflect.Map:
+		if !v.IsNil() {
+			key := copyKey{ptr: v.Pointer(), typ: v.Type()}
+			if m, ok := cp[key]; ok {
+				return m
+			}
+			c.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+			cp[key] = c
+			for iter := v.MapRange(); iter.Next(); {
+				c.SetMapIndex(cp.copy(iter.Key()), cp.copy(iter.Value()))
+			}
+		}
+	case reflect.Struct:
+		c.Set(v)
+		for i := range v.NumField() {
+			if v.Type().Field(i).IsExported() {
+				c.Field(i).Set(cp.copy(v.Field(i)))
+			}
+		}
+	default:
+		c.Set(v)
+	}
+	return c
+}
@@ -0,0 +1,53 @@
+package flagfx_test
+
+import (
+	"testing"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+type node struct {
+	Name string
+	Next *node
+	Tags map[string][]string
+}
+
+type snapshotFlags struct {
+	Head   *node
+	Shared *node
+}
+
+func TestSnapshot(t *testing.T) {
+	head := &node{Name: "a", Tags: map[string][]string{"k": {"v"}}}
+	head.Next = &node{Name: "b", Next: head}
+	orig := &snapshotFlags{Head: head, Shared: head.Next}
+
+	var snap snapshotFlags
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), nil),
+		flagfx.Provide(func() *snapshotFlags { return orig }),
+		flagfx.Snapshot[snapshotFlags](),
+		fx.Populate(&snap),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if snap.Head == orig.Head || snap.Head.Next == orig.Head.Next {
+		t.Fatal("snapshot shares its pointers with the original")
+	}
+	if snap.Head.Next.Next != snap.Head {
+		t.Error("snapshot does not preserve the cycle")
+	}
+	if snap.Shared != snap.Head.Next {
+		t.Error("snapshot does not preserve shared pointers")
+	}
+	orig.Head.Tags["k"][0] = "changed"
+	orig.Head.Name = "changed"
+	if snap.Head.Name != "a" || snap.Head.Tags["k"][0] != "v" {
+		t.Errorf("snapshot changed with the original: %q, %q", snap.Head.Name, snap.Head.Tags["k"])
+	}
+}
@@ -0,0 +1,48 @@
+package flagfx
+
+import (
+	"flag"
+	"fmt"
+	"time"
+)
+
+// ParseStats describes a completed parse, e.g. for a startup metric.
+type ParseStats struct {
+	Duration    time.Duration // The time spent parsing and applying fallbacks.
+	Flags       int           // The number of flags registered.
+	CommandLine int           // The number of flags set on the command line.
+	Preset      int           // The number of flags set by a preset.
+	Env         int           // The number of flags set from the environment.
+	Config      int           // The number of flags set from config files or sources.
+}
+
+// OnParse returns an fx.Option that calls fn inside the barrier once the flag
+// set has been parsed and fallbacks such as EnvPrefix and ConfigFile have been
+// applied. A panic in fn is recovered and fails the app with an error instead
+// of crashing it.
+func OnParse(fn func(stats ParseStats)) Option {
+	return addHook(stageParsed, func(p *parser) (err error) {
+		stats := ParseStats{
+			Duration:    time.Since(p.start),
+			CommandLine: len(p.cli),
+		}
+		p.fs.VisitAll(func(*flag.Flag) { stats.Flags++ })
+		for _, src := range p.sources {
+			switch src {
+			case SourcePreset:
+				stats.Preset++
+			case SourceEnv:
+				stats.Env++
+			case SourceConfig:
+				stats.Config++
+			}
+		}
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("flagfx: OnParse callback panicked: %v", r)
+			}
+		}()
+		fn(stats)
+		return nil
+	})
+}
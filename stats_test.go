@@ -0,0 +1,67 @@
+package flagfx_test
+
+import (
+	"strings"
+	"testing"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestOnParse(t *testing.T) {
+	tests := []struct {
+		args   []string
+		called bool
+		want   flagfx.ParseStats
+		err    string
+	}{
+		{nil, true, flagfx.ParseStats{Flags: 4, Env: 1, Config: 1}, ""},
+		{[]string{"-port=1", "-host=cli"}, true, flagfx.ParseStats{Flags: 4, CommandLine: 2, Config: 1}, ""},
+		{[]string{"-workers=x"}, false, flagfx.ParseStats{}, "-workers"},
+		{[]string{"-panic"}, true, flagfx.ParseStats{}, "flagfx: OnParse callback panicked: boom"},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		fs.Int("port", 80, "")
+		fs.String("host", "localhost", "")
+		fs.Int("workers", 4, "")
+		shouldPanic := fs.Bool("panic", false, "")
+		var (
+			called bool
+			got    flagfx.ParseStats
+		)
+		err := fx.New(
+			fx.NopLogger,
+			flagfx.ModuleFor(fs, tt.args),
+			flagfx.Env(func(key string) (string, bool) { return "example.com", key == "APP_HOST" }),
+			flagfx.EnvPrefix("app"),
+			flagfx.ConfigFile(writeConfig(t, "workers=8\n")),
+			flagfx.OnParse(func(stats flagfx.ParseStats) {
+				called = true
+				if *shouldPanic {
+					panic("boom")
+				}
+				got = stats
+			}),
+			fx.Invoke(func(flagfx.Ready) {}),
+		).Err()
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: error %v, want %q", tt.args, err, tt.err)
+			}
+		} else if err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+		}
+		if called != tt.called {
+			t.Errorf("%q: called = %t, want %t", tt.args, called, tt.called)
+		}
+		if got.Duration < 0 {
+			t.Errorf("%q: negative duration %s", tt.args, got.Duration)
+		}
+		got.Duration = 0
+		if got != tt.want {
+			t.Errorf("%q: stats %+v, want %+v", tt.args, got, tt.want)
+		}
+	}
+}
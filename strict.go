@@ -0,0 +1,64 @@
+package flagfx
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"go.uber.org/fx"
+)
+
+// StrictBarrier returns an fx.Option that catches flags registered after
+// parsing, which is what happens when a flag-defining constructor is provided
+// with fx.Provide instead of Provide: its flags are never parsed and always
+// keep their defaults.
+//
+// The flag package offers no way to reject a registration, and fx calls such a
+// constructor whenever it is first needed, up to the last fx.Invoke. So the
+// flag set is locked by recording its flags once parsing has completed, and
+// the check runs when the app starts, once every constructor of the app has
+// been called: fx.New succeeds, and Start fails with an error naming every
+// flag registered after parsing. The check is an OnStart hook appended by an
+// fx.Invoke of this option, so the OnStart hooks appended by earlier invokes
+// run before it; list StrictBarrier before the invokes of the app to fail
+// before they start. Flags registered by OnStart hooks are not caught. The
+// check is a single pass over the flags and only runs when this option is
+// added.
+func StrictBarrier() fx.Option {
+	return fx.Options(
+		addHook(stageParsed, func(p *parser) error {
+			p.locked = make(map[string]bool)
+			p.fs.VisitAll(func(f *flag.Flag) {
+				p.locked[f.Name] = true
+			})
+			return nil
+		}),
+		fx.Invoke(func(r result, lc fx.Lifecycle) {
+			lc.Append(fx.Hook{
+				OnStart: func(context.Context) error {
+					return r.p.checkLocked()
+				},
+			})
+		}),
+	)
+}
+
+// checkLocked reports an error if flags were registered after the flag set
+// was locked.
+func (p *parser) checkLocked() error {
+	if p.locked == nil {
+		// The flag set was parsed elsewhere, so the barrier never locked it.
+		return nil
+	}
+	var late []string
+	p.fs.VisitAll(func(f *flag.Flag) {
+		if !p.locked[f.Name] {
+			late = append(late, f.Name)
+		}
+	})
+	if len(late) > 0 {
+		return fmt.Errorf("flagfx: flags %s were registered after parsing; "+
+			"register them in a constructor passed to flagfx.Provide instead of fx.Provide", joinFlags(late))
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package flagfx_test
+
+import (
+	"context"
+	"flag"
+	"strings"
+	"testing"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestStrictBarrier(t *testing.T) {
+	type late struct{ port *int }
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), nil),
+		flagfx.StrictBarrier(),
+		flagfx.Provide(func(fs *flag.FlagSet) *string { return fs.String("host", "", "") }),
+		// Provided with fx.Provide, so the flag is registered after parsing.
+		fx.Provide(func(fs *flag.FlagSet, _ *string) *late { return &late{port: fs.Int("port", 80, "")} }),
+		fx.Invoke(func(*late) {}),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatalf("fx.New: %v", err)
+	}
+	err := app.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "flags -port were registered after parsing") {
+		t.Errorf("Start: %v, want the late -port reported", err)
+	}
+}
+
+func TestStrictBarrierAllowsProvide(t *testing.T) {
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), nil),
+		flagfx.StrictBarrier(),
+		flagfx.Provide(func(fs *flag.FlagSet) *string { return fs.String("host", "", "") }),
+		fx.Invoke(func(*string) {}),
+	)
+	if err := app.Start(context.Background()); err != nil {
+		t.Errorf("Start: %v", err)
+	}
+	if err := app.Stop(context.Background()); err != nil {
+		t.Errorf("Stop: %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package flagfx
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.uber.org/fx"
+)
+
+// Command is the name of the subcommand selected on the command line, or
+// empty if the app has no subcommands registered with Subcommand.
+//
+// Like Positional, Command must be consumed via fx.Provide or fx.Invoke.
+type Command string
+
+// command is a subcommand contributed to the flagfx barrier.
+type command struct {
+	name string
+	opts []fx.Option
+	app  *fx.App // The app of the subcommand, once selected.
+}
+
+// Subcommand returns an fx.Option registering a subcommand, such as serve in
+// "app -v serve -port 8080". The first positional argument remaining after
+// parsing the default flag set selects the subcommand, whose own flag set is
+// then parsed with the arguments following its name. A missing or unknown
+// subcommand is a usage error.
+//
+// The options of the selected subcommand are wired by the barrier into an fx
+// app of their own, which is started and stopped with the app. Inside it,
+// *flag.FlagSet is the subcommand's flag set, Positional holds the arguments
+// remaining after its flags and Command names the subcommand. Constructors
+// passed to Provide within opts register their flags on that set, so
+// subcommands may share flag names:
+//
+//	fx.New(
+//		flagfx.Module,
+//		flagfx.Subcommand("serve",
+//			flagfx.Provide(newServeFlags),
+//			fx.Invoke(serve),
+//		),
+//		flagfx.Subcommand("migrate", ...),
+//	)
+//
+// The options of the subcommands that were not selected are never wired, so
+// neither their constructors nor their functions given to fx.Invoke run.
+// Options taking part in parsing, such as Required, apply to the subcommand's
+// flag set when passed in opts. The app of a subcommand only shares the
+// environment, the Exiter and the warning output with the app; other
+// dependencies, such as a logger, are provided by passing their modules in
+// opts as well.
+func Subcommand(name string, opts ...fx.Option) fx.Option {
+	return fx.Options(
+		fx.Supply(fx.Annotated{
+			Group:  "flagfx_commands",
+			Target: &command{name: name, opts: opts},
+		}),
+		// Nothing in the app may depend on the flags, so make sure that the
+		// barrier runs to select the subcommand.
+		fx.Invoke(func(Ready) {}),
+	)
+}
+
+// parseCommand selects the subcommand named by the first positional argument
+// of the parsed flag set and builds its app, which parses its flag set with the
+// remaining arguments. The app is started and stopped with lc.
+func (p *parser) parseCommand(commands []*command, lc fx.Lifecycle) error {
+	if len(commands) == 0 {
+		return nil
+	}
+	names := make([]string, len(commands))
+	for i, c := range commands {
+		names[i] = c.name
+	}
+	slices.Sort(names)
+
+	args := p.fs.Args()
+	if len(args) == 0 {
+		return p.usageError(fmt.Errorf("missing subcommand, expected one of %s", strings.Join(names, ", ")))
+	}
+	i := slices.IndexFunc(commands, func(c *command) bool { return c.name == args[0] })
+	if i < 0 {
+		return p.usageError(fmt.Errorf("unknown subcommand %q, expected one of %s", args[0], strings.Join(names, ", ")))
+	}
+	c := commands[i]
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.SetOutput(p.fs.Output())
+	c.app = fx.New(
+		fx.NopLogger,
+		newModule(fx.Supply(fs, Arguments(args[1:]))),
+		fx.Replace(EnvLookup(p.env), Exiter(p.exit), warningOutput{w: p.warn}, parseContext{ctx: p.ctx}),
+		fx.Decorate(func() Command { return Command(c.name) }),
+		addHook(stageSetup, func(q *parser) error {
+			q.helpCode = p.helpCode
+			if p.tolerateUnknown {
+				q.tolerateUnknown, q.warnUnknown = true, q.warnUnknown || p.warnUnknown
+			}
+			return nil
+		}),
+		// Parse the flags of the subcommand before anything else runs.
+		fx.Invoke(func(Ready) {}),
+		fx.Options(c.opts...),
+	)
+	if err := c.app.Err(); err != nil {
+		return err
+	}
+	lc.Append(fx.Hook{OnStart: c.app.Start, OnStop: c.app.Stop})
+	p.command = c
+	return nil
+}
+
+// commandName returns the name of the selected subcommand.
+func (p *parser) commandName() Command {
+	if p.command == nil {
+		return ""
+	}
+	return Command(p.command.name)
+}
+
+// usageError reports err and the usage of the flag set the way the flag
+// package reports a parse error, and returns it as a ParseError.
+func (p *parser) usageError(err error) error {
+	fmt.Fprintln(p.fs.Output(), err)
+	printUsage(p.fs)
+	return ParseError{Args: p.args, Err: err}
+}
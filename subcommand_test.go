@@ -0,0 +1,104 @@
+package flagfx_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"reflect"
+	"testing"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+type serveFlags struct {
+	Port *int
+}
+
+func TestSubcommand(t *testing.T) {
+	var ran []string
+	newApp := func(args ...string) *fx.App {
+		ran = nil
+		return fx.New(
+			fx.NopLogger,
+			flagfx.ModuleFor(newTestFlagSet(), args),
+			flagfx.Subcommand("serve",
+				flagfx.Provide(func(fs *flag.FlagSet) *serveFlags {
+					ran = append(ran, "serve constructor")
+					return &serveFlags{Port: fs.Int("port", 80, "")}
+				}),
+				flagfx.Required("port"),
+				fx.Invoke(func(f *serveFlags, cmd flagfx.Command, pos flagfx.Positional) {
+					ran = append(ran, "serve invoke")
+					if *f.Port != 8080 || cmd != "serve" || !reflect.DeepEqual(pos, flagfx.Positional{"x"}) {
+						t.Errorf("serve got -port=%d, Command %q, Positional %q", *f.Port, cmd, pos)
+					}
+				}),
+			),
+			flagfx.Subcommand("migrate",
+				flagfx.Provide(func(fs *flag.FlagSet) *int {
+					ran = append(ran, "migrate constructor")
+					return fs.Int("port", 0, "")
+				}),
+				fx.Invoke(func(*int) { ran = append(ran, "migrate invoke") }),
+			),
+		)
+	}
+
+	app := newApp("serve", "-port=8080", "x")
+	if err := app.Err(); err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+	if want := []string{"serve constructor", "serve invoke"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("serve ran %q, want %q", ran, want)
+	}
+
+	app = newApp("migrate")
+	if err := app.Err(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if want := []string{"migrate constructor", "migrate invoke"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("migrate ran %q, want %q", ran, want)
+	}
+
+	for _, args := range [][]string{{"serve"}, {"unknown"}, {}} {
+		var perr flagfx.ParseError
+		app = newApp(args...)
+		if err := app.Err(); err == nil {
+			t.Errorf("%q: app built without error", args)
+		} else if len(args) == 0 || args[0] != "serve" {
+			if !errors.As(err, &perr) {
+				t.Errorf("%q: error %v is not a ParseError", args, err)
+			}
+		}
+		if len(ran) > 0 && ran[len(ran)-1] != "serve constructor" {
+			t.Errorf("%q ran %q", args, ran)
+		}
+	}
+}
+
+func TestSubcommandLifecycle(t *testing.T) {
+	var started, stopped bool
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), []string{"serve"}),
+		flagfx.Subcommand("serve",
+			fx.Invoke(func(lc fx.Lifecycle) {
+				lc.Append(fx.Hook{
+					OnStart: func(context.Context) error { started = true; return nil },
+					OnStop:  func(context.Context) error { stopped = true; return nil },
+				})
+			}),
+		),
+	)
+	if err := app.Start(context.Background()); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := app.Stop(context.Background()); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if !started || !stopped {
+		t.Errorf("subcommand started %v, stopped %v, want both", started, stopped)
+	}
+}
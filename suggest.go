@@ -0,0 +1,74 @@
+package flagfx
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// SuggestUnknown returns an fx.Option that augments the error for a flag that
+// is not defined with the closest registered flag, if any is close enough:
+//
+//	flag provided but not defined: -loglvl; did you mean -log-level?
+//
+// The flag package reports the error, along with the usage, to the output of
+// the flag set, so the suggestion is written there as well, e.g.
+//
+//	did you mean -log-level?
+//
+// following the usage; Run therefore shows it without reporting the error.
+// Since the flag package exits on its own with flag.ExitOnError, it only
+// applies to flag sets using flag.ContinueOnError, see ErrorHandling. Hidden
+// flags are never suggested.
+func SuggestUnknown() Option {
+	return addHook(stageSetup, func(p *parser) error {
+		p.suggest = true
+		return nil
+	})
+}
+
+// withSuggestion augments the error for an undefined flag returned by Parse
+// with the closest registered flag, and writes the suggestion to the output of
+// the flag set.
+func (p *parser) withSuggestion(err error) error {
+	name, ok := strings.CutPrefix(err.Error(), "flag provided but not defined: -")
+	if !ok {
+		return err
+	}
+	// Allow about one edit for every two characters, but at least two.
+	best, bestDist := "", max(2, len(name)/2)+1
+	p.fs.VisitAll(func(f *flag.Flag) {
+		if p.isHidden(f.Name) {
+			return
+		}
+		if d := levenshtein(name, f.Name); d < bestDist && d < len(name) {
+			best, bestDist = f.Name, d
+		}
+	})
+	if best == "" {
+		return err
+	}
+	fmt.Fprintf(p.fs.Output(), "did you mean -%s?\n", best)
+	return fmt.Errorf("%w; did you mean -%s?", err, best)
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,43 @@
+package flagfx_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestSuggestUnknown(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string // The suggestion, if any.
+	}{
+		{"-loglvl=debug", "-log-level"},
+		{"-prot=1", "-port"},
+		{"-xyz", ""},
+		{"-debug-secret", ""}, // Hidden flags are never suggested.
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		fs.String("log-level", "info", "")
+		fs.Int("port", 80, "")
+		fs.Bool("debug-secrets", false, "")
+		_, err := flagfx.ParseArgs(fs, []string{tt.arg}, flagfx.SuggestUnknown(), flagfx.Hidden("debug-secrets"))
+		var perr flagfx.ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("%s: error %v is not a ParseError", tt.arg, err)
+			continue
+		}
+		msg := err.Error()
+		if !strings.Contains(msg, "flag provided but not defined") {
+			t.Errorf("%s: error %q", tt.arg, msg)
+		}
+		if tt.want == "" && strings.Contains(msg, "did you mean") {
+			t.Errorf("%s: unexpected suggestion in %q", tt.arg, msg)
+		}
+		if tt.want != "" && !strings.HasSuffix(msg, "; did you mean "+tt.want+"?") {
+			t.Errorf("%s: error %q, want the suggestion %s", tt.arg, msg, tt.want)
+		}
+	}
+}
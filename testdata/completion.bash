@@ -0,0 +1,20 @@
+# bash completion for app
+_app_completion() {
+	local cur="${COMP_WORDS[COMP_CWORD]}" prev="${COMP_WORDS[COMP_CWORD-1]}"
+	case "$cur" in
+	-format=*|--format=*)
+		COMPREPLY=($(compgen -P "${cur%%=*}=" -W 'text json' -- "${cur#*=}"))
+		return
+		;;
+	esac
+	case "$prev" in
+	-format|--format)
+		COMPREPLY=($(compgen -W 'text json' -- "$cur"))
+		return
+		;;
+	esac
+	if [[ "$cur" == -* ]]; then
+		COMPREPLY=($(compgen -W '-addr -format -verbose' -- "$cur"))
+	fi
+}
+complete -o default -F _app_completion 'app'
@@ -0,0 +1,177 @@
+package flagfx
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"weak"
+)
+
+// Usage returns an fx.Option that sets the usage function of the active flag
+// set (the default one or the one supplied via FlagSet) before parsing.
+// The function is called with the flag set, e.g. when -h is requested.
+// Combined with ErrorHandling(flag.ContinueOnError), the usage is printed and
+// the app exits cleanly, see HelpExitCode.
+func Usage(fn func(fs *flag.FlagSet)) Option {
+	return addHook(stageSetup, func(p *parser) error {
+		fs := p.fs
+		fs.Usage = func() { fn(fs) }
+		return nil
+	})
+}
+
+// HelpExitCode returns an fx.Option that sets the exit code used when help is
+// requested with -h or -help. By default it is 0.
+//
+// With flag.ContinueOnError, flagfx treats a help request as a clean shutdown:
+// once the usage has been printed, it calls the Exiter with the exit code, so
+// asking for help never looks like a failure. If the Exiter returns, e.g. in
+// tests, the app fails with a ParseError wrapping flag.ErrHelp instead.
+func HelpExitCode(code int) Option {
+	return addHook(stageSetup, func(p *parser) error {
+		p.helpCode = code
+		return nil
+	})
+}
+
+// UsageNote is a line of text appended to the usage output, after the list of
+// flags, such as an example or a hint. Modules contribute notes to the value
+// group "flagfx_usage", so each can add to the help without owning the usage
+// function:
+//
+//	fx.Supply(fx.Annotated{
+//		Group:  "flagfx_usage",
+//		Target: flagfx.UsageNote("Use -version to print the version."),
+//	})
+//
+// Notes are printed in sorted order, separated from the flags by a blank line.
+// The usage can be printed while parsing, so notes must not be contributed by
+// constructors passed to Provide, whose results are only available once the
+// flags are parsed; use fx.Supply or fx.Provide instead. Like EnvUsage, the
+// notes also apply to a function set with Usage.
+type UsageNote string
+
+// wrapUsageNotes wraps the usage function of the flag set so that the notes
+// are appended to its output.
+func (p *parser) wrapUsageNotes() {
+	if len(p.notes) == 0 {
+		return
+	}
+	notes := slices.Sorted(slices.Values(p.notes))
+	rewriteUsage(p.fs, func(usage []byte) []byte {
+		usage = append(usage, '\n')
+		for _, note := range notes {
+			usage = append(usage, note+"\n"...)
+		}
+		return usage
+	})
+}
+
+// baseUsage maps a weak pointer to a flag set whose usage function flagfx
+// wrapped to the function it had before, so that a flag set parsed again, e.g.
+// with ForceParse or by several apps, is wrapped once rather than once per
+// parse.
+var baseUsage sync.Map
+
+// wrapUsage wraps the usage function of the flag set with the additions of
+// the options, such as EnvUsage, Alias, Hidden, Group and the usage notes.
+// The wrappers of an earlier parse are removed first, unless the usage
+// function has been replaced since.
+func (p *parser) wrapUsage() error {
+	key := weak.Make(p.fs)
+	if base, ok := baseUsage.Load(key); ok && isMarkedUsage(p.fs.Usage) {
+		p.fs.Usage = base.(func())
+	}
+	base := p.fs.Usage
+	p.wrapEnvUsage()
+	p.wrapAliasUsage()
+	if err := p.wrapHiddenUsage(); err != nil {
+		return err
+	}
+	if err := p.wrapGroupUsage(); err != nil {
+		return err
+	}
+	p.wrapUsageNotes()
+	p.fs.Usage = markUsage(p.fs, p.fs.Usage)
+	if _, loaded := baseUsage.Swap(key, base); !loaded {
+		runtime.AddCleanup(p.fs, func(key weak.Pointer[flag.FlagSet]) {
+			baseUsage.Delete(key)
+		}, key)
+	}
+	return nil
+}
+
+// markUsage returns the usage function of the flag set wrapped by flagfx,
+// recognized by isMarkedUsage.
+func markUsage(fs *flag.FlagSet, usage func()) func() {
+	return func() {
+		if usage != nil {
+			usage()
+			return
+		}
+		defaultUsage(fs)
+	}
+}
+
+// _markedUsagePC is the code pointer of the functions returned by markUsage.
+var _markedUsagePC = reflect.ValueOf(markUsage(nil, nil)).Pointer()
+
+// isMarkedUsage reports whether the usage function was returned by markUsage.
+func isMarkedUsage(usage func()) bool {
+	return usage != nil && reflect.ValueOf(usage).Pointer() == _markedUsagePC
+}
+
+// printUsage prints the usage of the flag set, falling back to the default
+// output of the flag package when the flag set has no usage function.
+func printUsage(fs *flag.FlagSet) {
+	if fs.Usage != nil {
+		fs.Usage()
+		return
+	}
+	defaultUsage(fs)
+}
+
+// defaultUsage prints the usage of the flag set like the flag package does
+// when the flag set has no usage function.
+func defaultUsage(fs *flag.FlagSet) {
+	fmt.Fprintf(fs.Output(), "Usage of %s:\n", fs.Name())
+	fs.PrintDefaults()
+}
+
+// rewriteUsage wraps the usage function of the flag set so that its output is
+// passed through rewrite before it is written.
+func rewriteUsage(fs *flag.FlagSet, rewrite func(usage []byte) []byte) {
+	usage := fs.Usage
+	fs.Usage = func() {
+		out := fs.Output()
+		var buf bytes.Buffer
+		fs.SetOutput(&buf)
+		defer func() {
+			fs.SetOutput(out)
+			out.Write(rewrite(buf.Bytes()))
+		}()
+		if usage != nil {
+			usage()
+			return
+		}
+		defaultUsage(fs)
+	}
+}
+
+// usageEntry reports whether the line of usage output starts the entry of a
+// flag in the format of PrintDefaults, "  -name ...", and returns the name.
+// The entry continues with the lines starting with "    \t" that follow.
+func usageEntry(line []byte) (string, bool) {
+	rest, ok := bytes.CutPrefix(line, []byte("  -"))
+	if !ok {
+		return "", false
+	}
+	name, _, _ := strings.Cut(strings.TrimRight(string(rest), "\n"), " ")
+	name, _, _ = strings.Cut(name, "\t")
+	return name, true
+}
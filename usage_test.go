@@ -0,0 +1,59 @@
+package flagfx_test
+
+import (
+	"bytes"
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestUsageWrappedOnce(t *testing.T) {
+	var out bytes.Buffer
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.SetOutput(&out)
+	fs.String("log-level", "info", "log level")
+	fs.Bool("debug", false, "debug mode")
+	opts := []flagfx.Option{
+		flagfx.ForceParse(),
+		flagfx.EnvPrefix("APP"),
+		flagfx.Hidden("debug"),
+		flagfx.Group("Logging", "log-level"),
+	}
+	for range 3 {
+		if _, err := flagfx.ParseArgs(fs, nil, opts...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fs.Usage()
+	usage := out.String()
+	for _, want := range []string{"(env: APP_LOG_LEVEL)", "Logging"} {
+		if n := strings.Count(usage, want); n != 1 {
+			t.Errorf("usage has %d times %q, want 1:\n%s", n, want, usage)
+		}
+	}
+	if strings.Contains(usage, "-debug") {
+		t.Errorf("usage lists the hidden -debug:\n%s", usage)
+	}
+}
+
+func TestUsageReplacedBetweenParses(t *testing.T) {
+	var out bytes.Buffer
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.SetOutput(&out)
+	fs.String("log-level", "info", "log level")
+	opts := []flagfx.Option{flagfx.ForceParse(), flagfx.EnvPrefix("APP")}
+	if _, err := flagfx.ParseArgs(fs, nil, opts...); err != nil {
+		t.Fatal(err)
+	}
+	fs.Usage = func() { out.WriteString("custom usage\n"); fs.PrintDefaults() }
+	if _, err := flagfx.ParseArgs(fs, nil, opts...); err != nil {
+		t.Fatal(err)
+	}
+	fs.Usage()
+	usage := out.String()
+	if !strings.Contains(usage, "custom usage") || strings.Count(usage, "(env: APP_LOG_LEVEL)") != 1 {
+		t.Errorf("usage:\n%s", usage)
+	}
+}
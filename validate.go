@@ -0,0 +1,271 @@
+package flagfx
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ValidationError is returned when the parsed flags or positional arguments
+// fail a check such as Required, MutuallyExclusive, Validate or NoPositional.
+// Like a ParseError, it means that the command line is wrong, so Run exits
+// with code 2. Errors in declaring the checks, such as naming an undefined
+// flag, are configuration errors instead.
+type ValidationError struct {
+	Err error // The failed check, e.g. the missing required flags.
+}
+
+// Error implements the error interface.
+func (e ValidationError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// validationErrorf returns a ValidationError with the formatted message.
+func validationErrorf(format string, args ...any) error {
+	return ValidationError{Err: fmt.Errorf(format, args...)}
+}
+
+// Required returns an fx.Option that fails the app unless each of the named
+// flags was explicitly set. The check runs inside the barrier after parsing,
+// so dependents are never instantiated when a required flag is missing.
+// Naming a flag that is not defined in the flag set is a configuration error.
+func Required(names ...string) Option {
+	return addHook(stageParsed, func(p *parser) error {
+		if err := lookupAll(p.fs, "required", names); err != nil {
+			return err
+		}
+		set := setFlags(p.fs)
+		var missing []string
+		for _, name := range names {
+			if !set[name] {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			return validationErrorf("flagfx: missing required flags: %s", joinFlags(missing))
+		}
+		return nil
+	})
+}
+
+// RequiredIf returns an fx.Option that fails the app unless the named flag was
+// explicitly set whenever cond reports true for the parsed flag set, for
+// example to require -auth-token when -auth=true. The option can be used
+// several times; all conditions are evaluated and the flags missing across
+// them are reported together in a single error.
+func RequiredIf(name string, cond func(fs *flag.FlagSet) bool) Option {
+	return addHook(stageSetup, func(p *parser) error {
+		p.requiredIf = append(p.requiredIf, requiredIf{name: name, cond: cond})
+		return nil
+	})
+}
+
+// requiredIf is a flag that is required when a condition holds.
+type requiredIf struct {
+	name string
+	cond func(fs *flag.FlagSet) bool
+}
+
+// checkRequiredIf verifies the conditionally required flags registered with
+// RequiredIf, aggregating the missing ones into one error.
+func (p *parser) checkRequiredIf() error {
+	set := setFlags(p.fs)
+	var missing []string
+	for _, r := range p.requiredIf {
+		if err := lookupAll(p.fs, "required", []string{r.name}); err != nil {
+			return err
+		}
+		if r.cond(p.fs) && !set[r.name] && !slices.Contains(missing, r.name) {
+			missing = append(missing, r.name)
+		}
+	}
+	if len(missing) > 0 {
+		return validationErrorf("flagfx: missing conditionally required flags: %s", joinFlags(missing))
+	}
+	return nil
+}
+
+// MutuallyExclusive returns an fx.Option that fails the app if more than one
+// of the named flags was set, for example both -json and -yaml. The error
+// names exactly the conflicting flags that were provided. The option can be
+// used several times to declare independent groups. Naming a flag that is not
+// defined in the flag set is a configuration error.
+func MutuallyExclusive(names ...string) Option {
+	return addHook(stageParsed, func(p *parser) error {
+		if err := lookupAll(p.fs, "mutually exclusive", names); err != nil {
+			return err
+		}
+		set := setFlags(p.fs)
+		var conflicting []string
+		for _, name := range names {
+			if set[name] {
+				conflicting = append(conflicting, name)
+			}
+		}
+		if len(conflicting) > 1 {
+			return validationErrorf("flagfx: flags %s are mutually exclusive", joinFlags(conflicting))
+		}
+		return nil
+	})
+}
+
+// Validate returns an fx.Option that checks the values of flags after parsing,
+// without writing a custom flag.Value. Rules are keyed by flag name and are
+// called with the string form of the flag's effective value, including values
+// applied from fallbacks such as EnvPrefix. All rules are evaluated and their
+// failures are joined into one error, with one line per invalid flag, e.g.
+//
+//	flagfx: invalid value "0" for flag -port: must be positive
+//
+// The option can be used several times. A rule for a flag that is not defined
+// in the flag set is a configuration error.
+func Validate(rules map[string]func(value string) error) Option {
+	return addHook(stageParsed, func(p *parser) error {
+		names := slices.Sorted(maps.Keys(rules))
+		if err := lookupAll(p.fs, "validated", names); err != nil {
+			return err
+		}
+		var errs []error
+		for _, name := range names {
+			value := p.fs.Lookup(name).Value.String()
+			if err := rules[name](value); err != nil {
+				errs = append(errs, fmt.Errorf("flagfx: invalid value %q for flag -%s: %w", value, name, err))
+			}
+		}
+		if len(errs) > 0 {
+			return ValidationError{Err: errors.Join(errs...)}
+		}
+		return nil
+	})
+}
+
+// MaxOccurrences returns an fx.Option that fails the app if the named
+// repeatable flag was given more than n times, e.g.
+//
+//	flagfx: flag -include was given 4 times, at most 3 are allowed
+//
+// The flag's value must count its occurrences with a method
+//
+//	Occurrences() int
+//
+// as StringSlice, the value of DefineSlice, does; otherwise, as when naming a
+// flag that is not defined, it is a configuration error.
+func MaxOccurrences(name string, n int) Option {
+	return addHook(stageParsed, func(p *parser) error {
+		if err := lookupAll(p.fs, "repeatable", []string{name}); err != nil {
+			return err
+		}
+		value := p.fs.Lookup(name).Value
+		seeded, isSeeded := value.(*seededValue)
+		if isSeeded {
+			value = seeded.Value
+		}
+		v, ok := value.(interface{ Occurrences() int })
+		if !ok {
+			return fmt.Errorf("flagfx: flag -%s does not count its occurrences", name)
+		}
+		got := v.Occurrences()
+		if isSeeded && !seeded.changed {
+			got = 0 // The values are the default.
+		}
+		if got > n {
+			return validationErrorf("flagfx: flag -%s was given %d times, at most %d are allowed", name, got, n)
+		}
+		return nil
+	})
+}
+
+// NoPositional returns an fx.Option that fails the app if any positional
+// arguments remain after parsing, for commands that take none. The error
+// lists the unexpected arguments, e.g.
+//
+//	flagfx: expected no positional arguments, got 2: "a", "b"
+func NoPositional() Option {
+	return addHook(stageParsed, func(p *parser) error {
+		if args := p.fs.Args(); len(args) > 0 {
+			return validationErrorf("flagfx: expected no positional arguments, got %d: %s", len(args), quoteArgs(args))
+		}
+		return nil
+	})
+}
+
+// ExactPositional returns an fx.Option that fails the app unless exactly n
+// positional arguments remain after parsing, e.g.
+//
+//	flagfx: expected exactly 2 positional arguments, got 1
+func ExactPositional(n int) Option {
+	return addHook(stageParsed, func(p *parser) error {
+		if got := p.fs.NArg(); got != n {
+			return validationErrorf("flagfx: expected exactly %s, got %d", positionalArgs(n), got)
+		}
+		return nil
+	})
+}
+
+// MinPositional returns an fx.Option that fails the app unless at least n
+// positional arguments remain after parsing, e.g.
+//
+//	flagfx: expected at least 1 positional argument, got 0
+func MinPositional(n int) Option {
+	return addHook(stageParsed, func(p *parser) error {
+		if got := p.fs.NArg(); got < n {
+			return validationErrorf("flagfx: expected at least %s, got %d", positionalArgs(n), got)
+		}
+		return nil
+	})
+}
+
+// positionalArgs renders n positional arguments, e.g. "1 positional argument".
+func positionalArgs(n int) string {
+	if n == 1 {
+		return "1 positional argument"
+	}
+	return fmt.Sprintf("%d positional arguments", n)
+}
+
+// quoteArgs renders arguments as a comma-separated list of quoted strings.
+func quoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = strconv.Quote(arg)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// lookupAll reports an error if any of the named flags is not defined.
+// The kind describes the option referring to the flags, e.g. "required".
+func lookupAll(fs *flag.FlagSet, kind string, names []string) error {
+	for _, name := range names {
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("flagfx: %s flag -%s is not defined", kind, name)
+		}
+	}
+	return nil
+}
+
+// setFlags returns the names of the flags that have been set.
+func setFlags(fs *flag.FlagSet) map[string]bool {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	return set
+}
+
+// joinFlags renders flag names as a sorted, comma-separated list of -name.
+func joinFlags(names []string) string {
+	names = slices.Sorted(slices.Values(names))
+	for i, name := range names {
+		names[i] = "-" + name
+	}
+	return strings.Join(names, ", ")
+}
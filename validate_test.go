@@ -0,0 +1,187 @@
+package flagfx_test
+
+import (
+	"errors"
+	"flag"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/lftk/flagfx"
+)
+
+func positive(value string) error {
+	if n, err := strconv.Atoi(value); err != nil || n <= 0 {
+		return errors.New("must be positive")
+	}
+	return nil
+}
+
+func TestValidate(t *testing.T) {
+	rules := map[string]func(string) error{
+		"port":    positive,
+		"workers": positive,
+		"name": func(value string) error {
+			if value == "" {
+				return errors.New("must not be empty")
+			}
+			return nil
+		},
+	}
+
+	fs := newTestFlagSet()
+	fs.Int("port", 80, "")
+	fs.Int("workers", 4, "")
+	fs.String("name", "app", "")
+	if _, err := flagfx.ParseArgs(fs, []string{"-port=8080"}, flagfx.Validate(rules)); err != nil {
+		t.Errorf("valid flags: %v", err)
+	}
+
+	fs = newTestFlagSet()
+	fs.Int("port", 80, "")
+	fs.Int("workers", 4, "")
+	fs.String("name", "app", "")
+	_, err := flagfx.ParseArgs(fs, []string{"-port=0", "-workers=-1", "-name=x"}, flagfx.Validate(rules))
+	if err == nil {
+		t.Fatal("invalid flags: no error")
+	}
+	want := []string{
+		`flagfx: invalid value "0" for flag -port: must be positive`,
+		`flagfx: invalid value "-1" for flag -workers: must be positive`,
+	}
+	if got := strings.Split(err.Error(), "\n"); !slicesEqual(got, want) {
+		t.Errorf("error lines %q, want %q", got, want)
+	}
+}
+
+func TestValidateUndefinedFlag(t *testing.T) {
+	fs := newTestFlagSet()
+	_, err := flagfx.ParseArgs(fs, nil, flagfx.Validate(map[string]func(string) error{"port": positive}))
+	var verr flagfx.ValidationError
+	if err == nil || errors.As(err, &verr) {
+		t.Errorf("error %v, want a configuration error", err)
+	}
+}
+
+func TestMaxOccurrences(t *testing.T) {
+	for n := range 5 {
+		args := slices.Repeat([]string{"-include=x"}, n)
+		fs := newTestFlagSet()
+		flagfx.DefineSlice(fs, "include", "")
+		_, err := flagfx.ParseArgs(fs, args, flagfx.MaxOccurrences("include", 3))
+		if n <= 3 {
+			if err != nil {
+				t.Errorf("%d occurrences: %v", n, err)
+			}
+			continue
+		}
+		var verr flagfx.ValidationError
+		if want := "flagfx: flag -include was given 4 times, at most 3 are allowed"; !errors.As(err, &verr) || err.Error() != want {
+			t.Errorf("%d occurrences: error %v, want %q", n, err, want)
+		}
+	}
+}
+
+func TestMaxOccurrencesNotRepeatable(t *testing.T) {
+	for _, name := range []string{"port", "undefined"} {
+		fs := newTestFlagSet()
+		fs.Int("port", 80, "")
+		_, err := flagfx.ParseArgs(fs, nil, flagfx.MaxOccurrences(name, 1))
+		var verr flagfx.ValidationError
+		if err == nil || errors.As(err, &verr) {
+			t.Errorf("-%s: error %v, want a configuration error", name, err)
+		}
+	}
+}
+
+func flagIs(name, value string) func(fs *flag.FlagSet) bool {
+	return func(fs *flag.FlagSet) bool {
+		return fs.Lookup(name).Value.String() == value
+	}
+}
+
+func TestRequiredIf(t *testing.T) {
+	tests := []struct {
+		args []string
+		err  string
+	}{
+		{nil, ""},
+		{[]string{"-auth"}, "flagfx: missing conditionally required flags: -auth-token"},
+		{[]string{"-auth", "-auth-token=x"}, ""},
+		{[]string{"-auth", "-tls"}, "flagfx: missing conditionally required flags: -auth-token, -tls-cert"},
+		{[]string{"-auth", "-tls", "-tls-cert=c"}, "flagfx: missing conditionally required flags: -auth-token"},
+		{[]string{"-mode=prod", "-tls", "-tls-cert=c"}, "flagfx: missing conditionally required flags: -admin, -auth-token"},
+		// Setting the flag to its default still counts as setting it.
+		{[]string{"-mode=prod", "-admin=root"}, ""},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		fs.Bool("auth", false, "")
+		fs.String("auth-token", "", "")
+		fs.Bool("tls", false, "")
+		fs.String("tls-cert", "", "")
+		fs.String("mode", "dev", "")
+		fs.String("admin", "root", "")
+		_, err := flagfx.ParseArgs(fs, tt.args,
+			flagfx.RequiredIf("auth-token", flagIs("auth", "true")),
+			flagfx.RequiredIf("tls-cert", flagIs("tls", "true")),
+			flagfx.RequiredIf("auth-token", flagIs("tls", "true")), // Reported once.
+			flagfx.RequiredIf("admin", flagIs("mode", "prod")),
+		)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%q: %v", tt.args, err)
+			}
+			continue
+		}
+		var verr flagfx.ValidationError
+		if !errors.As(err, &verr) || err.Error() != tt.err {
+			t.Errorf("%q: error %v, want %q", tt.args, err, tt.err)
+		}
+	}
+}
+
+func TestRequiredIfUndefinedFlag(t *testing.T) {
+	_, err := flagfx.ParseArgs(newTestFlagSet(), nil, flagfx.RequiredIf("token", func(*flag.FlagSet) bool { return false }))
+	var verr flagfx.ValidationError
+	if err == nil || errors.As(err, &verr) {
+		t.Errorf("error %v, want a configuration error", err)
+	}
+}
+
+func TestPositional(t *testing.T) {
+	tests := []struct {
+		name string
+		opt  flagfx.Option
+		args []string
+		err  string
+	}{
+		{"NoPositional", flagfx.NoPositional(), []string{"-v"}, ""},
+		{"NoPositional", flagfx.NoPositional(), []string{"-v", "a", "b"}, `flagfx: expected no positional arguments, got 2: "a", "b"`},
+		{"NoPositional", flagfx.NoPositional(), []string{"--", "a"}, ""}, // Passed through.
+		{"ExactPositional", flagfx.ExactPositional(2), []string{"a", "b"}, ""},
+		{"ExactPositional", flagfx.ExactPositional(2), []string{"a"}, "flagfx: expected exactly 2 positional arguments, got 1"},
+		{"ExactPositional", flagfx.ExactPositional(1), []string{"a", "b", "c"}, "flagfx: expected exactly 1 positional argument, got 3"},
+		{"ExactPositional", flagfx.ExactPositional(0), nil, ""},
+		{"MinPositional", flagfx.MinPositional(1), []string{"a"}, ""},
+		{"MinPositional", flagfx.MinPositional(1), []string{"a", "b"}, ""},
+		{"MinPositional", flagfx.MinPositional(1), []string{"-v"}, "flagfx: expected at least 1 positional argument, got 0"},
+		{"MinPositional", flagfx.MinPositional(3), []string{"a", "b"}, "flagfx: expected at least 3 positional arguments, got 2"},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		fs.Bool("v", false, "")
+		_, err := flagfx.ParseArgs(fs, tt.args, tt.opt)
+		if tt.err == "" {
+			if err != nil {
+				t.Errorf("%s %q: %v", tt.name, tt.args, err)
+			}
+			continue
+		}
+		var verr flagfx.ValidationError
+		if !errors.As(err, &verr) || err.Error() != tt.err {
+			t.Errorf("%s %q: error %v, want %q", tt.name, tt.args, err, tt.err)
+		}
+	}
+}
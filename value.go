@@ -0,0 +1,441 @@
+package flagfx
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// StringSlice is a flag.Value that accumulates the values of a repeatable
+// flag, so -tag=a -tag=b yields ["a", "b"].
+type StringSlice []string
+
+// String returns the values joined by commas.
+func (s *StringSlice) String() string {
+	if s == nil {
+		return ""
+	}
+	return strings.Join(*s, ",")
+}
+
+// Set appends the value.
+func (s *StringSlice) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// Occurrences returns the number of times the flag was set, see
+// MaxOccurrences.
+func (s *StringSlice) Occurrences() int {
+	if s == nil {
+		return 0
+	}
+	return len(*s)
+}
+
+// DefineSlice registers a repeatable string flag with the flag set and returns
+// a pointer to the collected values, in the order they were given.
+func DefineSlice(fs *flag.FlagSet, name string, usage string) *[]string {
+	var values []string
+	fs.Var((*StringSlice)(&values), name, usage)
+	recordDefault(fs, name, func() { values = nil })
+	return &values
+}
+
+// StringMap is a flag.Value that accumulates key=value pairs, given as
+// comma-separated groups and across repeated flags, so -label a=1,b=2
+// -label c=3 yields {"a": "1", "b": "2", "c": "3"}. A key given again
+// overwrites the previous value, so the last one wins.
+type StringMap map[string]string
+
+// String returns the pairs sorted by key and joined by commas.
+func (m *StringMap) String() string {
+	if m == nil {
+		return ""
+	}
+	pairs := make([]string, 0, len(*m))
+	for _, k := range slices.Sorted(maps.Keys(*m)) {
+		pairs = append(pairs, k+"="+(*m)[k])
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set adds the comma-separated key=value pairs.
+func (m *StringMap) Set(value string) error {
+	pairs := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || k == "" {
+			return fmt.Errorf("expected key=value, got %q", pair)
+		}
+		pairs[k] = v
+	}
+	if *m == nil {
+		*m = make(StringMap)
+	}
+	maps.Copy(*m, pairs)
+	return nil
+}
+
+// DefineMap registers a repeatable key=value flag with the flag set and returns
+// a pointer to the collected pairs, see StringMap.
+func DefineMap(fs *flag.FlagSet, name, usage string) *map[string]string {
+	var m map[string]string
+	fs.Var((*StringMap)(&m), name, usage)
+	recordDefault(fs, name, func() { m = nil })
+	return &m
+}
+
+// seededValue wraps the flag.Value of a flag accumulating its values, such as
+// StringSlice or StringMap, once a default has been seeded into it, e.g. with
+// a default tag of Bind or with Defaults. Like pflag does, the first value set
+// replaces the default instead of extending it, so a default of [a] and
+// -tag=b yield [b].
+type seededValue struct {
+	flag.Value
+	clear   func() // Removes the default from the value.
+	changed bool   // Whether a value was set since the default was seeded.
+}
+
+// String returns the value.
+func (v *seededValue) String() string {
+	// The flag package may call String on a zero value.
+	if v == nil || v.Value == nil {
+		return ""
+	}
+	return v.Value.String()
+}
+
+// Set replaces the default on the first call, and accumulates the value.
+func (v *seededValue) Set(value string) error {
+	if !v.changed {
+		v.clear()
+		v.changed = true
+	}
+	return v.Value.Set(value)
+}
+
+// seedDefault sets the default of the flag to the values, given as on the
+// command line, replacing the current default. The values of a flag
+// accumulating them, such as StringSlice, are kept apart from the values set
+// later, see seededValue.
+func seedDefault(f *flag.Flag, values []string) error {
+	value := f.Value
+	seeded, ok := value.(*seededValue)
+	if ok {
+		value = seeded.Value
+	}
+	var clear func()
+	switch v := value.(type) {
+	case *StringSlice:
+		clear = func() { *v = nil }
+	case *StringMap:
+		clear = func() { *v = nil }
+	}
+	if clear != nil {
+		clear()
+	}
+	for _, s := range values {
+		if err := value.Set(s); err != nil {
+			return err
+		}
+	}
+	if clear != nil {
+		if seeded == nil {
+			seeded = &seededValue{Value: value}
+			f.Value = seeded
+		}
+		seeded.clear, seeded.changed = clear, false
+	}
+	f.DefValue = value.String()
+	return nil
+}
+
+// OptionalString is a flag.Value holding a string that may be unset, for
+// merging configuration where an explicitly empty value, as in -name=, must
+// not be mistaken for a missing one. Valid reports whether the flag was set.
+type OptionalString struct {
+	Value string
+	Valid bool
+}
+
+// String returns the value.
+func (s *OptionalString) String() string {
+	if s == nil {
+		return ""
+	}
+	return s.Value
+}
+
+// Set sets the value and marks it as valid.
+func (s *OptionalString) Set(value string) error {
+	*s = OptionalString{Value: value, Valid: true}
+	return nil
+}
+
+// DefineOptionalString registers a string flag with the flag set and returns a
+// pointer to its value, which is only valid if the flag was set, on the
+// command line or by a fallback such as EnvPrefix.
+func DefineOptionalString(fs *flag.FlagSet, name, usage string) *OptionalString {
+	var s OptionalString
+	fs.Var(&s, name, usage)
+	recordDefault(fs, name, func() { s = OptionalString{} })
+	return &s
+}
+
+// Count is a flag.Value counting how many times a flag was given, so
+// -v -v -v yields 3. It is a boolean flag, so -v needs no argument.
+// An explicit -v=true counts as one more occurrence, -v=false resets the count
+// to zero and -v=n sets it to the non-negative integer n.
+type Count int
+
+// String returns the count.
+func (c *Count) String() string {
+	if c == nil {
+		return "0"
+	}
+	return strconv.Itoa(int(*c))
+}
+
+// Set counts an occurrence of the flag.
+func (c *Count) Set(value string) error {
+	if b, err := strconv.ParseBool(value); err == nil {
+		if b {
+			*c++
+		} else {
+			*c = 0
+		}
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return errors.New("must be a boolean or a non-negative count")
+	}
+	*c = Count(n)
+	return nil
+}
+
+// IsBoolFlag reports that the flag needs no argument.
+func (c *Count) IsBoolFlag() bool {
+	return true
+}
+
+// DefineCount registers a counting flag with the flag set and returns a pointer
+// to the number of times it was given.
+func DefineCount(fs *flag.FlagSet, name, usage string) *int {
+	var n int
+	fs.Var((*Count)(&n), name, usage)
+	recordDefault(fs, name, func() { n = 0 })
+	return &n
+}
+
+// enumValue is a flag.Value that only accepts one of the allowed values.
+type enumValue struct {
+	value   *string
+	allowed []string
+}
+
+// String returns the current value.
+func (e *enumValue) String() string {
+	if e == nil || e.value == nil {
+		return ""
+	}
+	return *e.value
+}
+
+// Set sets the value if it is allowed.
+func (e *enumValue) Set(value string) error {
+	if !slices.Contains(e.allowed, value) {
+		return fmt.Errorf("must be one of %s", strings.Join(e.allowed, ", "))
+	}
+	*e.value = value
+	return nil
+}
+
+// DefineEnum registers a string flag with the flag set that only accepts one of
+// the allowed values, and returns a pointer to its value. Any other value is
+// rejected when parsing, e.g.
+//
+//	invalid value "trace" for flag -log-level: must be one of debug, info, warn, error
+//
+// The allowed values are appended to the usage message. The default is used as
+// is, without being checked against the allowed values.
+func DefineEnum(fs *flag.FlagSet, name, def string, allowed []string, usage string) *string {
+	value := def
+	usage = fmt.Sprintf("%s (one of %s)", usage, strings.Join(allowed, ", "))
+	fs.Var(&enumValue{value: &value, allowed: slices.Clone(allowed)}, name, usage)
+	recordDefault(fs, name, func() { value = def })
+	return &value
+}
+
+// rangeValue is a flag.Value for a number that must lie within [min, max].
+type rangeValue[T int | float64] struct {
+	value    *T
+	min, max T
+	parse    func(s string) (T, error)
+}
+
+// String returns the current value.
+func (r *rangeValue[T]) String() string {
+	if r == nil || r.value == nil {
+		return ""
+	}
+	return fmt.Sprint(*r.value)
+}
+
+// Set sets the value if it is within the range.
+func (r *rangeValue[T]) Set(value string) error {
+	v, err := r.parse(value)
+	if err != nil {
+		return errors.New("parse error")
+	}
+	if v < r.min || v > r.max {
+		return fmt.Errorf("value %v out of range [%v, %v]", v, r.min, r.max)
+	}
+	*r.value = v
+	return nil
+}
+
+// DefineIntRange registers an int flag with the flag set that only accepts
+// values from min to max, inclusive, and returns a pointer to its value. Any
+// other value is rejected when parsing, e.g.
+//
+//	invalid value "0" for flag -workers: value 0 out of range [1, 64]
+//
+// The range is appended to the usage message. The default is used as is,
+// without being checked against the range.
+func DefineIntRange(fs *flag.FlagSet, name string, def, min, max int, usage string) *int {
+	return defineRange(fs, name, def, min, max, usage, func(s string) (int, error) {
+		v, err := strconv.ParseInt(s, 0, strconv.IntSize)
+		return int(v), err
+	})
+}
+
+// DefineFloatRange is like DefineIntRange, but for a float64 flag.
+func DefineFloatRange(fs *flag.FlagSet, name string, def, min, max float64, usage string) *float64 {
+	return defineRange(fs, name, def, min, max, usage, func(s string) (float64, error) {
+		return strconv.ParseFloat(s, 64)
+	})
+}
+
+// defineRange registers a flag for a number within [min, max].
+func defineRange[T int | float64](fs *flag.FlagSet, name string, def, min, max T, usage string, parse func(string) (T, error)) *T {
+	value := def
+	usage = fmt.Sprintf("%s (range [%v, %v])", usage, min, max)
+	fs.Var(&rangeValue[T]{value: &value, min: min, max: max, parse: parse}, name, usage)
+	recordDefault(fs, name, func() { value = def })
+	return &value
+}
+
+// negatedBool is a boolean flag.Value that sets the inverse of its value on
+// the target flag, backing the -no-name form of a negatable flag.
+type negatedBool struct {
+	fs     *flag.FlagSet
+	target string
+}
+
+// String returns the negated value of the target flag.
+func (b *negatedBool) String() string {
+	// The flag package may call String on a zero value.
+	if b == nil || b.fs == nil {
+		return "false"
+	}
+	v, _ := strconv.ParseBool(b.fs.Lookup(b.target).Value.String())
+	return strconv.FormatBool(!v)
+}
+
+// Set sets the inverse of the value on the target flag, through the flag set,
+// so that the target counts as explicitly set, like with an alias.
+func (b *negatedBool) Set(value string) error {
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		return errors.New("parse error")
+	}
+	return b.fs.Set(b.target, strconv.FormatBool(!v))
+}
+
+// IsBoolFlag reports that the flag needs no argument.
+func (b *negatedBool) IsBoolFlag() bool {
+	return true
+}
+
+// DefineBoolNegatable registers a boolean flag with the flag set that can be
+// given as -name or negated as -no-name, and returns a pointer to its value.
+// Both forms write to the same value and the last one given wins, so
+// -no-feature -feature yields true. Either form counts as setting -name
+// explicitly, e.g. for Lookup, Required and MutuallyExclusive. Both forms are
+// listed in the usage output.
+func DefineBoolNegatable(fs *flag.FlagSet, name string, def bool, usage string) *bool {
+	value := def
+	fs.BoolVar(&value, name, def, usage)
+	fs.Var(&negatedBool{fs: fs, target: name}, "no-"+name, fmt.Sprintf("negate -%s", name))
+	recordDefault(fs, name, func() { value = def })
+	return &value
+}
+
+// optionVar registers a flag of an option of flagfx, such as -preset or
+// -flagfx-dry-run, holding value. A flag of the same type registered by a
+// previous parse of the flag set, e.g. with ForceParse, is reset to value and
+// reused instead, since registering it again would panic.
+func optionVar[T any, PT interface {
+	*T
+	flag.Value
+}](fs *flag.FlagSet, name, usage string, value T) PT {
+	if f := fs.Lookup(name); f != nil {
+		if v, ok := f.Value.(PT); ok {
+			*v = value
+			f.Usage = usage
+			return v
+		}
+	}
+	v := PT(&value)
+	fs.Var(v, name, usage)
+	return v
+}
+
+// optionBool is the flag.Value of a boolean flag registered with optionVar.
+type optionBool bool
+
+// String returns the value.
+func (b *optionBool) String() string {
+	if b == nil {
+		return "false"
+	}
+	return strconv.FormatBool(bool(*b))
+}
+
+// Set parses the value.
+func (b *optionBool) Set(value string) error {
+	v, err := strconv.ParseBool(value)
+	if err != nil {
+		return errors.New("parse error")
+	}
+	*b = optionBool(v)
+	return nil
+}
+
+// IsBoolFlag reports that the flag needs no argument.
+func (b *optionBool) IsBoolFlag() bool {
+	return true
+}
+
+// optionString is the flag.Value of a string flag registered with optionVar.
+type optionString string
+
+// String returns the value.
+func (s *optionString) String() string {
+	if s == nil {
+		return ""
+	}
+	return string(*s)
+}
+
+// Set sets the value.
+func (s *optionString) Set(value string) error {
+	*s = optionString(value)
+	return nil
+}
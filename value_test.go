@@ -0,0 +1,338 @@
+package flagfx_test
+
+import (
+	"flag"
+	"maps"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go.uber.org/fx"
+
+	"github.com/lftk/flagfx"
+)
+
+func TestDefineBoolNegatable(t *testing.T) {
+	tests := []struct {
+		args []string
+		def  bool
+		want bool
+		set  bool
+	}{
+		{nil, true, true, false},
+		{nil, false, false, false},
+		{[]string{"-feature"}, false, true, true},
+		{[]string{"-no-feature"}, true, false, true},
+		{[]string{"-no-feature=false"}, false, true, true},
+		{[]string{"-feature=false"}, true, false, true},
+		{[]string{"-no-feature", "-feature"}, false, true, true},
+		{[]string{"-feature", "-no-feature"}, false, false, true},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		feature := flagfx.DefineBoolNegatable(fs, "feature", tt.def, "")
+		res, err := flagfx.ParseArgs(fs, tt.args)
+		if err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if *feature != tt.want {
+			t.Errorf("%q: -feature = %v, want %v", tt.args, *feature, tt.want)
+		}
+		if set := res.Sources["feature"] == flagfx.SourceCommandLine; set != tt.set {
+			t.Errorf("%q: -feature set %v, want %v", tt.args, set, tt.set)
+		}
+		if got, want := fs.Lookup("no-feature").Value.String(), strconv.FormatBool(!*feature); got != want {
+			t.Errorf("%q: -no-feature = %s, want %s", tt.args, got, want)
+		}
+	}
+}
+
+func TestDefineBoolNegatableExplicit(t *testing.T) {
+	fs := newTestFlagSet()
+	flagfx.DefineBoolNegatable(fs, "feature", true, "")
+	fs.Bool("other", false, "")
+
+	if _, err := flagfx.ParseArgs(fs, []string{"-no-feature"}, flagfx.Required("feature")); err != nil {
+		t.Errorf("Required: %v", err)
+	}
+
+	fs = newTestFlagSet()
+	flagfx.DefineBoolNegatable(fs, "feature", true, "")
+	fs.Bool("other", false, "")
+	if _, err := flagfx.ParseArgs(fs, []string{"-no-feature", "-other"}, flagfx.MutuallyExclusive("feature", "other")); err == nil {
+		t.Error("MutuallyExclusive: -no-feature and -other accepted")
+	}
+
+	var lookup flagfx.Lookup
+	fs = newTestFlagSet()
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(fs, []string{"-no-feature"}),
+		flagfx.AddFlags(func(fs *flag.FlagSet) { flagfx.DefineBoolNegatable(fs, "feature", true, "") }),
+		fx.Populate(&lookup),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := lookup.Explicit("feature"); !ok || v != "false" {
+		t.Errorf("Explicit(feature) = %q, %v, want false, true", v, ok)
+	}
+}
+
+func TestDefineBoolNegatablePrefix(t *testing.T) {
+	var fs *flag.FlagSet
+	var lookup flagfx.Lookup
+	var feature *bool
+	app := fx.New(
+		fx.NopLogger,
+		flagfx.ModuleFor(newTestFlagSet(), []string{"-app.no-feature"}),
+		flagfx.Prefix("app")(func(fs *flag.FlagSet) *bool {
+			return flagfx.DefineBoolNegatable(fs, "feature", true, "")
+		}),
+		fx.Populate(&fs, &lookup, &feature),
+	)
+	if err := app.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if *feature || !lookup.Set("app.feature") {
+		t.Errorf("-app.feature = %v, set %v, want false, true", *feature, lookup.Set("app.feature"))
+	}
+}
+
+func TestDefineSlice(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{nil, nil},
+		{[]string{"-tag=a"}, []string{"a"}},
+		{[]string{"-tag=a", "-tag", "b,c", "-level=debug", "-tag=a"}, []string{"a", "b,c", "a"}},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		tags := flagfx.DefineSlice(fs, "tag", "")
+		level := flagfx.Define(fs, "level", "info", "")
+		if _, err := flagfx.ParseArgs(fs, tt.args); err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if !slicesEqual(*tags, tt.want) {
+			t.Errorf("%q: -tag = %q, want %q", tt.args, *tags, tt.want)
+		}
+		if got := fs.Lookup("tag").Value.String(); got != strings.Join(tt.want, ",") {
+			t.Errorf("%q: String() = %q", tt.args, got)
+		}
+		if len(tt.args) > 2 && *level != "debug" {
+			t.Errorf("%q: -level = %q, want debug", tt.args, *level)
+		}
+	}
+}
+
+func TestDefineCount(t *testing.T) {
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{nil, 0},
+		{[]string{"-v"}, 1},
+		{[]string{"-v", "-v", "-v"}, 3},
+		{[]string{"-v", "-v=true"}, 2},
+		{[]string{"-v", "-v", "-v=false"}, 0},
+		{[]string{"-v", "-v=false", "-v"}, 1},
+		{[]string{"-v=5"}, 5},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		v := flagfx.DefineCount(fs, "v", "")
+		if _, err := flagfx.ParseArgs(fs, tt.args); err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if *v != tt.want {
+			t.Errorf("%q: -v = %d, want %d", tt.args, *v, tt.want)
+		}
+	}
+
+	for _, arg := range []string{"-v=-1", "-v=many"} {
+		fs := newTestFlagSet()
+		flagfx.DefineCount(fs, "v", "")
+		if _, err := flagfx.ParseArgs(fs, []string{arg}); err == nil {
+			t.Errorf("%s: no error", arg)
+		}
+	}
+}
+
+func TestDefineEnum(t *testing.T) {
+	levels := []string{"debug", "info", "warn", "error"}
+	tests := []struct {
+		args []string
+		want string
+		err  string
+	}{
+		{nil, "info", ""},
+		{[]string{"-log-level=warn"}, "warn", ""},
+		{[]string{"-log-level=trace"}, "", `invalid value "trace" for flag -log-level: must be one of debug, info, warn, error`},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		level := flagfx.DefineEnum(fs, "log-level", "info", levels, "log level")
+		_, err := flagfx.ParseArgs(fs, tt.args)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: error %v, want %q", tt.args, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+		} else if *level != tt.want {
+			t.Errorf("%q: -log-level = %q, want %q", tt.args, *level, tt.want)
+		}
+	}
+
+	fs := newTestFlagSet()
+	flagfx.DefineEnum(fs, "log-level", "info", levels, "log level")
+	if got, want := fs.Lookup("log-level").Usage, "log level (one of debug, info, warn, error)"; got != want {
+		t.Errorf("usage = %q, want %q", got, want)
+	}
+}
+
+func TestDefineMap(t *testing.T) {
+	tests := []struct {
+		args []string
+		want map[string]string
+	}{
+		{nil, nil},
+		{[]string{"-label=a=1"}, map[string]string{"a": "1"}},
+		{[]string{"-label=a=1", "-label", "b=2"}, map[string]string{"a": "1", "b": "2"}},
+		{[]string{"-label=a=1,b=2", "-label=c=3,a=4"}, map[string]string{"a": "4", "b": "2", "c": "3"}},
+		{[]string{"-label=url=a=b,empty="}, map[string]string{"url": "a=b", "empty": ""}},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		labels := flagfx.DefineMap(fs, "label", "")
+		if _, err := flagfx.ParseArgs(fs, tt.args); err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if !maps.Equal(*labels, tt.want) {
+			t.Errorf("%q: -label = %v, want %v", tt.args, *labels, tt.want)
+		}
+	}
+
+	for _, arg := range []string{"-label=a", "-label=a=1,b", "-label==1"} {
+		fs := newTestFlagSet()
+		labels := flagfx.DefineMap(fs, "label", "")
+		_, err := flagfx.ParseArgs(fs, []string{arg})
+		if err == nil || !strings.Contains(err.Error(), "expected key=value") {
+			t.Errorf("%s: error %v, want a malformed pair reported", arg, err)
+		}
+		if len(*labels) > 0 {
+			t.Errorf("%s: -label = %v, want no pairs of the malformed value", arg, *labels)
+		}
+	}
+}
+
+func TestDefineOptionalString(t *testing.T) {
+	tests := []struct {
+		args  []string
+		want  flagfx.OptionalString
+		found bool
+	}{
+		{nil, flagfx.OptionalString{}, false},
+		{[]string{"-name="}, flagfx.OptionalString{Value: "", Valid: true}, true},
+		{[]string{"-name=x"}, flagfx.OptionalString{Value: "x", Valid: true}, true},
+	}
+	for _, tt := range tests {
+		var lookup flagfx.Lookup
+		fs := newTestFlagSet()
+		name := flagfx.DefineOptionalString(fs, "name", "")
+		app := fx.New(
+			fx.NopLogger,
+			flagfx.ModuleFor(fs, tt.args),
+			fx.Populate(&lookup),
+		)
+		if err := app.Err(); err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if *name != tt.want {
+			t.Errorf("%q: -name = %+v, want %+v", tt.args, *name, tt.want)
+		}
+		if value, found := lookup.Explicit("name"); value != tt.want.Value || found != tt.found {
+			t.Errorf("%q: Explicit = %q, %v, want %q, %v", tt.args, value, found, tt.want.Value, tt.found)
+		}
+	}
+}
+
+func TestDefineIntRange(t *testing.T) {
+	tests := []struct {
+		args []string
+		want int
+		err  string
+	}{
+		{nil, 4, ""},
+		{[]string{"-workers=1"}, 1, ""},
+		{[]string{"-workers=64"}, 64, ""},
+		{[]string{"-workers=0"}, 0, `invalid value "0" for flag -workers: value 0 out of range [1, 64]`},
+		{[]string{"-workers=65"}, 0, `invalid value "65" for flag -workers: value 65 out of range [1, 64]`},
+		{[]string{"-workers=x"}, 0, `invalid value "x" for flag -workers`},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		workers := flagfx.DefineIntRange(fs, "workers", 4, 1, 64, "number of workers")
+		_, err := flagfx.ParseArgs(fs, tt.args)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: error %v, want %q", tt.args, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if *workers != tt.want {
+			t.Errorf("%q: -workers = %d, want %d", tt.args, *workers, tt.want)
+		}
+	}
+
+	fs := newTestFlagSet()
+	flagfx.DefineIntRange(fs, "workers", 4, 1, 64, "number of workers")
+	if got, want := fs.Lookup("workers").Usage, "number of workers (range [1, 64])"; got != want {
+		t.Errorf("usage = %q, want %q", got, want)
+	}
+}
+
+func TestDefineFloatRange(t *testing.T) {
+	tests := []struct {
+		args []string
+		want float64
+		err  string
+	}{
+		{nil, 0.5, ""},
+		{[]string{"-ratio=0"}, 0, ""},
+		{[]string{"-ratio=1"}, 1, ""},
+		{[]string{"-ratio=-0.1"}, 0, "value -0.1 out of range [0, 1]"},
+		{[]string{"-ratio=1.5"}, 0, "value 1.5 out of range [0, 1]"},
+	}
+	for _, tt := range tests {
+		fs := newTestFlagSet()
+		ratio := flagfx.DefineFloatRange(fs, "ratio", 0.5, 0, 1, "sampling ratio")
+		_, err := flagfx.ParseArgs(fs, tt.args)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%q: error %v, want %q", tt.args, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+			continue
+		}
+		if *ratio != tt.want {
+			t.Errorf("%q: -ratio = %v, want %v", tt.args, *ratio, tt.want)
+		}
+	}
+}
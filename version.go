@@ -0,0 +1,77 @@
+package flagfx
+
+import (
+	"cmp"
+	"strconv"
+	"strings"
+
+	"go.uber.org/fx"
+)
+
+// Version is the version of the app, such as v1.4.0. It is empty unless set
+// with AppVersion, and used by DeprecatedUntil to tell whether a deprecated
+// flag has reached its removal.
+type Version string
+
+// defaultVersion provides the default Version, which is empty.
+// This can be replaced using the AppVersion option.
+func defaultVersion() Version {
+	return ""
+}
+
+// AppVersion returns an fx.Option that sets the Version of the app, typically
+// to the version a module such as verfx prints for -version.
+func AppVersion(v string) fx.Option {
+	return fx.Replace(Version(v))
+}
+
+// atLeast reports whether the version is v or later. Versions are compared
+// like semantic versions, with an optional "v" prefix, missing components
+// counting as 0, so 2 is 2.0.0, and a pre-release such as 2.0.0-rc.1 preceding
+// the release. If either version does not start with a number, such as an
+// empty or "unknown" version, atLeast reports false.
+func (ver Version) atLeast(v string) bool {
+	a, aPre, ok := splitVersion(string(ver))
+	if !ok {
+		return false
+	}
+	b, bPre, ok := splitVersion(v)
+	if !ok {
+		return false
+	}
+	for i := range max(len(a), len(b)) {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			return x > y
+		}
+	}
+	switch {
+	case aPre == "":
+		return true
+	case bPre == "":
+		return false
+	default:
+		return cmp.Compare(aPre, bPre) >= 0
+	}
+}
+
+// splitVersion splits a version into its numeric components and its
+// pre-release, ignoring build metadata.
+func splitVersion(v string) (nums []int, pre string, ok bool) {
+	v, _, _ = strings.Cut(strings.TrimPrefix(v, "v"), "+")
+	v, pre, _ = strings.Cut(v, "-")
+	for _, s := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil, "", false
+		}
+		nums = append(nums, n)
+	}
+	return nums, pre, true
+}
@@ -0,0 +1,212 @@
+package flagfx
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"go.uber.org/fx"
+)
+
+// Pre-calculated reflection types for efficiency.
+var (
+	_reflError = reflect.TypeFor[error]()
+	_reflOut   = reflect.TypeFor[fx.Out]()
+)
+
+// catchRedefined wraps the constructor fn so that the panic raised by the flag
+// package when a flag is registered twice is converted into an error naming the
+// duplicate flag. An error result is appended to fn's results if it has none.
+// Other panics are propagated, and values that are not functions are returned as is.
+func catchRedefined(fn any) any {
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func {
+		return fn
+	}
+
+	ft := fv.Type()
+	var in, out []reflect.Type
+	for i := range ft.NumIn() {
+		in = append(in, ft.In(i))
+	}
+	for i := range ft.NumOut() {
+		out = append(out, ft.Out(i))
+	}
+	hasErr := len(out) > 0 && out[len(out)-1] == _reflError
+	if !hasErr {
+		out = append(out, _reflError)
+	}
+
+	wrapper := reflect.MakeFunc(
+		reflect.FuncOf(in, out, ft.IsVariadic()),
+		func(args []reflect.Value) (results []reflect.Value) {
+			defer func() {
+				r := recover()
+				if r == nil {
+					return
+				}
+				name, ok := redefinedFlag(r)
+				if !ok {
+					panic(r)
+				}
+				results = make([]reflect.Value, len(out))
+				for i, t := range out {
+					results[i] = reflect.Zero(t)
+				}
+				err := fmt.Errorf("flagfx: flag %q already registered by another module", name)
+				results[len(out)-1] = reflect.ValueOf(&err).Elem()
+			}()
+
+			if ft.IsVariadic() {
+				results = fv.CallSlice(args)
+			} else {
+				results = fv.Call(args)
+			}
+			if !hasErr {
+				results = append(results, reflect.Zero(_reflError))
+			}
+			return results
+		},
+	)
+	return wrapper.Interface()
+}
+
+// redefinedFlag extracts the flag name from the panic value raised by
+// flag.FlagSet.Var for a duplicate flag, e.g. "app flag redefined: port".
+func redefinedFlag(r any) (string, bool) {
+	msg, ok := r.(string)
+	if !ok {
+		return "", false
+	}
+	_, name, ok := strings.Cut(msg, "flag redefined: ")
+	return name, ok
+}
+
+// annotateResults wraps the constructor of the fx.Annotated so that its results
+// are provided with the annotation's name or group, as fx.Provide would do.
+// The results are returned in an fx.Out struct, followed by fn's error if any.
+func annotateResults(a fx.Annotated) any {
+	fv := reflect.ValueOf(a.Target)
+	if fv.Kind() != reflect.Func {
+		return a
+	}
+
+	var tag reflect.StructTag
+	switch {
+	case a.Name != "":
+		tag = reflect.StructTag(fmt.Sprintf(`name:"%s"`, a.Name))
+	case a.Group != "":
+		tag = reflect.StructTag(fmt.Sprintf(`group:"%s"`, a.Group))
+	default:
+		return a.Target
+	}
+
+	ft := fv.Type()
+	var in []reflect.Type
+	for i := range ft.NumIn() {
+		in = append(in, ft.In(i))
+	}
+	fields := []reflect.StructField{
+		{Name: "Out", Type: _reflOut, Anonymous: true},
+	}
+	hasErr := false
+	for i := range ft.NumOut() {
+		t := ft.Out(i)
+		if t == _reflError && i == ft.NumOut()-1 {
+			hasErr = true
+			break
+		}
+		fields = append(fields, reflect.StructField{
+			Name: fmt.Sprintf("R%d", i),
+			Type: t,
+			Tag:  tag,
+		})
+	}
+	outType := reflect.StructOf(fields)
+	out := []reflect.Type{outType}
+	if hasErr {
+		out = append(out, _reflError)
+	}
+
+	wrapper := reflect.MakeFunc(
+		reflect.FuncOf(in, out, ft.IsVariadic()),
+		func(args []reflect.Value) []reflect.Value {
+			var results []reflect.Value
+			if ft.IsVariadic() {
+				results = fv.CallSlice(args)
+			} else {
+				results = fv.Call(args)
+			}
+			s := reflect.New(outType).Elem()
+			for i := 1; i < len(fields); i++ {
+				// Skip the embedded fx.Out field.
+				s.Field(i).Set(results[i-1])
+			}
+			if hasErr {
+				return []reflect.Value{s, results[len(results)-1]}
+			}
+			return []reflect.Value{s}
+		},
+	)
+	return wrapper.Interface()
+}
+
+// prefixFlags wraps the constructor fn so that the flags it registers are
+// renamed to prefix.name. Wherever fn accepts a *flag.FlagSet or a
+// FlagSetHandle, it receives a fresh flag set used for registration only,
+// whose flags are then registered on the original flag set under their
+// prefixed names, sharing their values.
+func prefixFlags(fn any, prefix string) any {
+	if a, ok := fn.(fx.Annotated); ok {
+		a.Target = prefixFlags(a.Target, prefix)
+		return a
+	}
+	fv := reflect.ValueOf(fn)
+	if fv.Kind() != reflect.Func {
+		return fn
+	}
+
+	ft := fv.Type()
+	wrapper := reflect.MakeFunc(ft, func(args []reflect.Value) []reflect.Value {
+		var parents, children []*flag.FlagSet
+		args = slices.Clone(args)
+		for i, arg := range args {
+			switch arg.Type() {
+			case _reflFlagSetPtr:
+				parent := arg.Interface().(*flag.FlagSet)
+				child := flag.NewFlagSet(parent.Name(), flag.ContinueOnError)
+				parents, children = append(parents, parent), append(children, child)
+				args[i] = reflect.ValueOf(child)
+			case _reflFlagSetHandle:
+				parent := arg.Interface().(FlagSetHandle).FlagSet()
+				child := flag.NewFlagSet(parent.Name(), flag.ContinueOnError)
+				parents, children = append(parents, parent), append(children, child)
+				args[i] = reflect.ValueOf(newFlagSetHandle(child))
+			}
+		}
+
+		var results []reflect.Value
+		if ft.IsVariadic() {
+			results = fv.CallSlice(args)
+		} else {
+			results = fv.Call(args)
+		}
+
+		for i, child := range children {
+			child.VisitAll(func(f *flag.Flag) {
+				name := prefix + "." + f.Name
+				value := f.Value
+				if b, ok := value.(*negatedBool); ok {
+					// Negate the prefixed flag on the original flag set.
+					value = &negatedBool{fs: parents[i], target: prefix + "." + b.target}
+				}
+				parents[i].Var(value, name, f.Usage)
+				moveDefault(child, f.Name, parents[i], name)
+			})
+		}
+		return results
+	})
+	return wrapper.Interface()
+}